@@ -0,0 +1,203 @@
+// cache.go implements the content-addressed compilation cache: a
+// CompilationCache stores the StreamMessage transcript and bitstream
+// produced for a given cache key (see cachekey.go), so an unchanged
+// resubmission can replay the cached run instead of re-invoking the
+// toolchain.
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cachedMessage pairs a StreamMessage with how long after the run
+// started it was published, so a cache hit can reconstruct plausible
+// timestamps for it on replay.
+type cachedMessage struct {
+	Msg     StreamMessage `json:"msg"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// CacheEntry is what's stored per cache key: the full progress
+// transcript plus the resulting bitstream.
+type CacheEntry struct {
+	Transcript []cachedMessage `json:"transcript"`
+	Bitstream  []byte          `json:"bitstream"`
+}
+
+// CompilationCache stores CacheEntry values keyed by the hash computed
+// by newCacheKey.
+type CompilationCache interface {
+	Get(key string) (*CacheEntry, bool)
+	Put(key string, entry *CacheEntry) error
+}
+
+// newCompilationCache selects an implementation based on the
+// COMPILATION_CACHE environment variable ("fs", the default, or "s3").
+func newCompilationCache() (CompilationCache, error) {
+	switch os.Getenv("COMPILATION_CACHE") {
+	case "s3":
+		return newS3Cache()
+	case "", "fs":
+		return newFSCache(
+			envOr("COMPILATION_CACHE_DIR", "/var/lib/fpga-compiler/cache"),
+			envInt64("COMPILATION_CACHE_MAX_BYTES", 10<<30), // 10GiB
+		)
+	default:
+		return nil, fmt.Errorf("unknown COMPILATION_CACHE %q", os.Getenv("COMPILATION_CACHE"))
+	}
+}
+
+// FSCache persists entries as JSON files under a root directory and
+// evicts the least-recently-used ones once their total size exceeds
+// maxBytes.
+type FSCache struct {
+	root     string
+	maxBytes int64
+
+	mu        sync.Mutex
+	order     *list.List // front = most recently used
+	elems     map[string]*list.Element
+	sizeBytes int64
+}
+
+type fsCacheEntryMeta struct {
+	key  string
+	size int64
+}
+
+func newFSCache(root string, maxBytes int64) (*FSCache, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create compilation cache root %s: %w", root, err)
+	}
+	c := &FSCache{
+		root:     root,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		elems:    make(map[string]*list.Element),
+	}
+	if err := c.rebuildIndex(); err != nil {
+		return nil, fmt.Errorf("failed to index existing compilation cache entries in %s: %w", root, err)
+	}
+	return c, nil
+}
+
+// rebuildIndex walks root for entries written by a previous process and
+// reconstructs order/elems/sizeBytes from them, oldest-modified first,
+// so a restart doesn't make every pre-existing entry invisible to Get
+// and untracked by evictLocked's size accounting (unlike S3Cache, which
+// always lists live from the bucket). Any entry over maxBytes at
+// startup is evicted immediately afterwards.
+func (c *FSCache) rebuildIndex() error {
+	entries, err := os.ReadDir(c.root)
+	if err != nil {
+		return err
+	}
+
+	type onDiskEntry struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var found []onDiskEntry
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			log.Printf("failed to stat compilation cache entry %s: %v", entry.Name(), err)
+			continue
+		}
+		found = append(found, onDiskEntry{
+			key:     strings.TrimSuffix(entry.Name(), ".json"),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range found {
+		meta := fsCacheEntryMeta{key: e.key, size: e.size}
+		c.elems[e.key] = c.order.PushFront(meta)
+		c.sizeBytes += e.size
+	}
+	c.evictLocked()
+	return nil
+}
+
+func (c *FSCache) path(key string) string {
+	return filepath.Join(c.root, key+".json")
+}
+
+func (c *FSCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	elem, ok := c.elems[key]
+	if ok {
+		c.order.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *FSCache) Put(key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path(key), data, 0644); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.elems[key]; ok {
+		c.sizeBytes -= elem.Value.(fsCacheEntryMeta).size
+		c.order.Remove(elem)
+	}
+	meta := fsCacheEntryMeta{key: key, size: int64(len(data))}
+	c.elems[key] = c.order.PushFront(meta)
+	c.sizeBytes += meta.size
+
+	c.evictLocked()
+	return nil
+}
+
+// evictLocked removes least-recently-used entries until sizeBytes is
+// back under maxBytes. The caller must hold c.mu.
+func (c *FSCache) evictLocked() {
+	for c.sizeBytes > c.maxBytes {
+		back := c.order.Back()
+		if back == nil {
+			return
+		}
+		meta := back.Value.(fsCacheEntryMeta)
+		os.Remove(c.path(meta.key))
+		c.order.Remove(back)
+		delete(c.elems, meta.key)
+		c.sizeBytes -= meta.size
+	}
+}
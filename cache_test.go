@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFSCachePutAndGet(t *testing.T) {
+	c, err := newFSCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("newFSCache: %v", err)
+	}
+
+	entry := &CacheEntry{Bitstream: []byte("bits")}
+	if err := c.Put("key1", entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok := c.Get("key1")
+	if !ok {
+		t.Fatal("Get did not find a just-written key")
+	}
+	if string(got.Bitstream) != "bits" {
+		t.Errorf("Bitstream = %q, want %q", got.Bitstream, "bits")
+	}
+
+	if _, ok := c.Get("missing"); ok {
+		t.Error("Get found a key that was never written")
+	}
+}
+
+func TestFSCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	root := t.TempDir()
+	entrySize := entryDiskSize(t, []byte("0123456789"))
+
+	c, err := newFSCache(root, 2*entrySize)
+	if err != nil {
+		t.Fatalf("newFSCache: %v", err)
+	}
+
+	mustPut(t, c, "a", []byte("0123456789"))
+	mustPut(t, c, "b", []byte("0123456789"))
+
+	// Touch "a" so "b" becomes the least-recently-used entry.
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be present before eviction")
+	}
+
+	// Putting a third entry must evict "b", not "a".
+	mustPut(t, c, "c", []byte("0123456789"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("least-recently-used entry b should have been evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("recently-used entry a should not have been evicted")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("newly written entry c should be present")
+	}
+}
+
+func TestFSCacheRebuildsIndexOnRestart(t *testing.T) {
+	root := t.TempDir()
+
+	c1, err := newFSCache(root, 1<<20)
+	if err != nil {
+		t.Fatalf("newFSCache: %v", err)
+	}
+	mustPut(t, c1, "old", []byte("old-data"))
+	mustPut(t, c1, "new", []byte("new-data"))
+
+	// Simulate a restart: a fresh FSCache over the same root directory
+	// should see both pre-existing entries without any Put calls.
+	c2, err := newFSCache(root, 1<<20)
+	if err != nil {
+		t.Fatalf("newFSCache (restart): %v", err)
+	}
+
+	got, ok := c2.Get("old")
+	if !ok {
+		t.Fatal("restarted FSCache did not index a pre-existing entry")
+	}
+	if string(got.Bitstream) != "old-data" {
+		t.Errorf("Bitstream = %q, want %q", got.Bitstream, "old-data")
+	}
+	if _, ok := c2.Get("new"); !ok {
+		t.Fatal("restarted FSCache did not index a second pre-existing entry")
+	}
+}
+
+func TestFSCacheRebuildEvictsOverflowOnRestart(t *testing.T) {
+	root := t.TempDir()
+	payload := []byte("0123456789")
+	entrySize := entryDiskSize(t, payload)
+
+	// Write three entries directly under a cache with plenty of room ...
+	c1, err := newFSCache(root, 10*entrySize)
+	if err != nil {
+		t.Fatalf("newFSCache: %v", err)
+	}
+	mustPut(t, c1, "oldest", payload)
+	time.Sleep(10 * time.Millisecond)
+	mustPut(t, c1, "middle", payload)
+	time.Sleep(10 * time.Millisecond)
+	mustPut(t, c1, "newest", payload)
+
+	// ... then reopen with a tighter budget that only fits one entry, and
+	// confirm the rebuild evicts by real mtime (oldest first), not
+	// whatever order os.ReadDir happens to return.
+	c2, err := newFSCache(root, entrySize)
+	if err != nil {
+		t.Fatalf("newFSCache (restart with smaller budget): %v", err)
+	}
+
+	if _, ok := c2.Get("oldest"); ok {
+		t.Error("oldest entry should have been evicted on restart")
+	}
+	if _, ok := c2.Get("middle"); ok {
+		t.Error("middle entry should have been evicted on restart")
+	}
+	if _, ok := c2.Get("newest"); !ok {
+		t.Error("newest entry should have survived the restart eviction")
+	}
+}
+
+func mustPut(t *testing.T, c *FSCache, key string, bitstream []byte) {
+	t.Helper()
+	if err := c.Put(key, &CacheEntry{Bitstream: bitstream}); err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+}
+
+// entryDiskSize returns the on-disk size of a CacheEntry wrapping
+// bitstream, so tests can size maxBytes exactly relative to the
+// payloads they write.
+func entryDiskSize(t *testing.T, bitstream []byte) int64 {
+	t.Helper()
+	data, err := json.Marshal(&CacheEntry{Bitstream: bitstream})
+	if err != nil {
+		t.Fatalf("marshaling probe entry: %v", err)
+	}
+	return int64(len(data))
+}
+
+func TestFSCacheNewRejectsUnwritableRoot(t *testing.T) {
+	root := t.TempDir()
+	file := root + "/not-a-dir"
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if _, err := newFSCache(file+"/child", 1<<20); err == nil {
+		t.Error("expected newFSCache to fail when root's parent is a regular file")
+	}
+}
@@ -0,0 +1,58 @@
+// cachekey.go computes the content-addressed key used by
+// CompilationCache: a SHA-256 hash over the canonicalized JSON of
+// everything that determines a compilation's output, so an unchanged
+// resubmission hits the cache and a toolchain upgrade invalidates
+// existing entries automatically.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+type cacheKeyInput struct {
+	Sources           map[string]string `json:"sources"`
+	TopModule         string            `json:"topModule"`
+	Target            string            `json:"target"`
+	Freq              int               `json:"freq"`
+	Seed              int               `json:"seed"`
+	TargetOptions     map[string]string `json:"targetOptions"`
+	ToolchainVersions map[string]string `json:"toolchainVersions"`
+}
+
+// newCacheKey hashes req together with toolchainVersions. encoding/json
+// marshals map keys in sorted order, so this is already canonical
+// without manually sorting Sources or TargetOptions.
+func newCacheKey(req CompileRequest, toolchainVersions map[string]string) string {
+	target := req.Target
+	if target == "" {
+		target = defaultTarget
+	}
+	freq := 12
+	if req.Freq != nil {
+		freq = *req.Freq
+	}
+	seed := 42
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	data, err := json.Marshal(cacheKeyInput{
+		Sources:           req.Sources,
+		TopModule:         req.TopModule,
+		Target:            target,
+		Freq:              freq,
+		Seed:              seed,
+		TargetOptions:     req.TargetOptions,
+		ToolchainVersions: toolchainVersions,
+	})
+	if err != nil {
+		// json.Marshal only fails on unsupported types, none of which
+		// appear in cacheKeyInput.
+		panic(err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestNewCacheKeyDeterministic(t *testing.T) {
+	freq, seed := 24, 7
+	req := CompileRequest{
+		Sources:       map[string]string{"a.v": "module a; endmodule", "b.v": "module b; endmodule"},
+		TopModule:     "top",
+		Freq:          &freq,
+		Seed:          &seed,
+		Target:        "ice40up5k",
+		TargetOptions: map[string]string{"package": "sg48"},
+	}
+	versions := map[string]string{"yosys": "0.40", "nextpnr-ice40": "0.7"}
+
+	if newCacheKey(req, versions) != newCacheKey(req, versions) {
+		t.Fatal("newCacheKey is not deterministic for identical input")
+	}
+}
+
+func TestNewCacheKeyIgnoresMapOrdering(t *testing.T) {
+	freq, seed := 24, 7
+	base := CompileRequest{
+		TopModule: "top",
+		Freq:      &freq,
+		Seed:      &seed,
+		Target:    "ice40up5k",
+	}
+
+	a := base
+	a.Sources = map[string]string{"a.v": "1", "b.v": "2"}
+	a.TargetOptions = map[string]string{"package": "sg48", "extra": "x"}
+
+	b := base
+	b.Sources = map[string]string{"b.v": "2", "a.v": "1"}
+	b.TargetOptions = map[string]string{"extra": "x", "package": "sg48"}
+
+	versions := map[string]string{"yosys": "0.40"}
+	if newCacheKey(a, versions) != newCacheKey(b, versions) {
+		t.Fatal("newCacheKey should be insensitive to map iteration order")
+	}
+}
+
+func TestNewCacheKeyChangesWithInput(t *testing.T) {
+	freq, seed := 24, 7
+	req := CompileRequest{
+		Sources:   map[string]string{"a.v": "module a; endmodule"},
+		TopModule: "top",
+		Freq:      &freq,
+		Seed:      &seed,
+		Target:    "ice40up5k",
+	}
+	versions := map[string]string{"yosys": "0.40"}
+	base := newCacheKey(req, versions)
+
+	withDifferentSource := req
+	withDifferentSource.Sources = map[string]string{"a.v": "module a; wire w; endmodule"}
+	if newCacheKey(withDifferentSource, versions) == base {
+		t.Error("changing a source file should change the cache key")
+	}
+
+	withDifferentTarget := req
+	withDifferentTarget.Target = "ecp5"
+	if newCacheKey(withDifferentTarget, versions) == base {
+		t.Error("changing the target should change the cache key")
+	}
+
+	newVersions := map[string]string{"yosys": "0.41"}
+	if newCacheKey(req, newVersions) == base {
+		t.Error("a toolchain version bump should invalidate the cache key")
+	}
+}
+
+func TestNewCacheKeyDefaultsMatchExplicitValues(t *testing.T) {
+	req := CompileRequest{TopModule: "top"}
+	versions := map[string]string{}
+
+	freq, seed := 12, 42
+	explicit := CompileRequest{TopModule: "top", Freq: &freq, Seed: &seed, Target: defaultTarget}
+
+	if newCacheKey(req, versions) != newCacheKey(explicit, versions) {
+		t.Error("omitted freq/seed/target should hash the same as their documented defaults")
+	}
+}
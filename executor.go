@@ -0,0 +1,98 @@
+// executor.go abstracts how a toolchain command actually runs: directly
+// as a child of the server process (DirectExecutor, the original
+// behavior), or isolated inside a namespace sandbox with resource limits
+// (SandboxExecutor, in sandbox.go). compile() depends only on the
+// Executor interface, never on exec.Cmd directly.
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// ExecResult describes how a command execution ended, including enough
+// detail to tell an OOM kill or rlimit violation apart from an ordinary
+// non-zero exit.
+type ExecResult struct {
+	Ran             bool // false if the process never started
+	ExitCode        int
+	OOMKilled       bool
+	RLimitViolation string // "cpu" (SIGXCPU) or "fsize" (SIGXFSZ); empty otherwise. An RLIMIT_AS breach is not detected here.
+	Err             error  // set if the process could not be started or waited on
+}
+
+// Ok reports whether the command ran to completion with a zero exit code.
+func (r ExecResult) Ok() bool {
+	return r.Err == nil && r.Ran && r.ExitCode == 0
+}
+
+// Executor runs a single toolchain command in workDir, streaming its
+// stdout/stderr to sink as it runs.
+type Executor interface {
+	Run(ctx context.Context, sink messageSink, workDir, command string, args []string) ExecResult
+}
+
+// DirectExecutor runs commands as direct children of the server process,
+// with no isolation beyond the OS's own process boundary.
+type DirectExecutor struct{}
+
+func (DirectExecutor) Run(ctx context.Context, sink messageSink, workDir, command string, args []string) ExecResult {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = workDir
+	return runCmd(sink, cmd, nil)
+}
+
+// runCmd starts cmd, streams its stdout/stderr to sink, waits for it,
+// and classifies the result from its exit status and terminating
+// signal. It's shared by DirectExecutor and SandboxExecutor. If onStart
+// is non-nil, it's invoked with the started process's PID right after
+// cmd.Start() succeeds, before Wait() is called — SandboxExecutor uses
+// this to add the process to its per-job cgroup.
+func runCmd(sink messageSink, cmd *exec.Cmd, onStart func(pid int)) ExecResult {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return ExecResult{Err: err}
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return ExecResult{Err: err}
+	}
+
+	if err := cmd.Start(); err != nil {
+		return ExecResult{Err: err}
+	}
+	if onStart != nil {
+		onStart(cmd.Process.Pid)
+	}
+
+	go streamOutput(sink, stdout, "stdout")
+	go streamOutput(sink, stderr, "stderr")
+
+	err = cmd.Wait()
+	if err == nil {
+		return ExecResult{Ran: true}
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return ExecResult{Err: err}
+	}
+
+	result := ExecResult{Ran: true, ExitCode: exitErr.ExitCode()}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		switch status.Signal() {
+		case syscall.SIGKILL:
+			// A bare SIGKILL with no matching rlimit signal is the usual
+			// signature of the kernel OOM killer. Inside a sandbox,
+			// SandboxExecutor.classifyFailure also reads the job cgroup's
+			// memory.events to catch OOM kills the exit status doesn't show.
+			result.OOMKilled = true
+		case syscall.SIGXCPU:
+			result.RLimitViolation = "cpu"
+		case syscall.SIGXFSZ:
+			result.RLimitViolation = "fsize"
+		}
+	}
+	return result
+}
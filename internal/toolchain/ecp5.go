@@ -0,0 +1,41 @@
+package toolchain
+
+import "fmt"
+
+// ECP5 targets Lattice ECP5 parts, synthesized with synth_ecp5 and
+// placed/routed with nextpnr-ecp5, then packed with ecppack.
+type ECP5 struct{}
+
+func init() { register(ECP5{}) }
+
+func (ECP5) Name() string                 { return "ecp5" }
+func (ECP5) ConstraintFile() string       { return "fpga.lpf" }
+func (ECP5) ConstraintSourcePath() string { return "/app/verilog/tt_fpga_ecp5.lpf" }
+func (ECP5) BitstreamFile() string        { return "output.bit" }
+func (ECP5) RequiredOptions() []string    { return []string{"device", "package"} }
+
+func (ECP5) SynthCommand(topModule string) string {
+	return fmt.Sprintf("synth_ecp5 -top %s -json output.json", topModule)
+}
+
+func (t ECP5) Steps(freq, seed int, opts map[string]string) ([]Step, error) {
+	return []Step{
+		{
+			Command: "nextpnr-ecp5",
+			Args: []string{
+				"--device", opts["device"],
+				"--package", opts["package"],
+				"--seed", fmt.Sprintf("%d", seed),
+				"--freq", fmt.Sprintf("%d", freq),
+				"--lpf", t.ConstraintFile(),
+				"--json", "output.json",
+				"--textcfg", "output.config",
+			},
+		},
+		{
+			Command:  "ecppack",
+			Args:     []string{"output.config", t.BitstreamFile()},
+			Artifact: t.BitstreamFile(),
+		},
+	}, nil
+}
@@ -0,0 +1,40 @@
+package toolchain
+
+import "fmt"
+
+// GowinLittleBee targets Gowin LittleBee-family parts (e.g. GW1N), placed
+// and routed via nextpnr's Himbaechel backend and packed with gowin_pack.
+type GowinLittleBee struct{}
+
+func init() { register(GowinLittleBee{}) }
+
+func (GowinLittleBee) Name() string                 { return "gowin-littlebee" }
+func (GowinLittleBee) ConstraintFile() string       { return "fpga.cst" }
+func (GowinLittleBee) ConstraintSourcePath() string { return "/app/verilog/tt_fpga_gowin.cst" }
+func (GowinLittleBee) BitstreamFile() string        { return "output.fs" }
+func (GowinLittleBee) RequiredOptions() []string    { return []string{"device"} }
+
+func (GowinLittleBee) SynthCommand(topModule string) string {
+	return fmt.Sprintf("synth_gowin -top %s -json output.json", topModule)
+}
+
+func (t GowinLittleBee) Steps(freq, seed int, opts map[string]string) ([]Step, error) {
+	device := opts["device"]
+
+	return []Step{
+		{
+			Command: "nextpnr-himbaechel",
+			Args: []string{
+				"--device", device,
+				"--vopt", "cst=" + t.ConstraintFile(),
+				"--json", "output.json",
+				"--write", "output.pnr.json",
+			},
+		},
+		{
+			Command:  "gowin_pack",
+			Args:     []string{"-d", device, "-o", t.BitstreamFile(), "output.pnr.json"},
+			Artifact: t.BitstreamFile(),
+		},
+	}, nil
+}
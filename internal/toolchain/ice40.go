@@ -0,0 +1,48 @@
+package toolchain
+
+import "fmt"
+
+// ICE40UP5K targets the Lattice iCE40 UP5K, synthesized with synth_ice40
+// and placed/routed with nextpnr-ice40. This is the toolchain the server
+// originally hardcoded.
+type ICE40UP5K struct{}
+
+func init() { register(ICE40UP5K{}) }
+
+func (ICE40UP5K) Name() string                 { return "ice40up5k" }
+func (ICE40UP5K) ConstraintFile() string       { return "fpga.pcf" }
+func (ICE40UP5K) ConstraintSourcePath() string { return "/app/verilog/tt_fpga_fabricfox.pcf" }
+func (ICE40UP5K) BitstreamFile() string        { return "output.bin" }
+func (ICE40UP5K) RequiredOptions() []string    { return nil }
+
+func (ICE40UP5K) SynthCommand(topModule string) string {
+	return fmt.Sprintf("synth_ice40 -top %s -json output.json", topModule)
+}
+
+func (t ICE40UP5K) Steps(freq, seed int, opts map[string]string) ([]Step, error) {
+	pkg := opts["package"]
+	if pkg == "" {
+		pkg = "sg48"
+	}
+
+	return []Step{
+		{
+			Command: "nextpnr-ice40",
+			Args: []string{
+				"--pcf-allow-unconstrained",
+				"--seed", fmt.Sprintf("%d", seed),
+				"--freq", fmt.Sprintf("%d", freq),
+				"--package", pkg,
+				"--up5k",
+				"--asc", "output.asc",
+				"--pcf", t.ConstraintFile(),
+				"--json", "output.json",
+			},
+		},
+		{
+			Command:  "icepack",
+			Args:     []string{"output.asc", t.BitstreamFile()},
+			Artifact: t.BitstreamFile(),
+		},
+	}, nil
+}
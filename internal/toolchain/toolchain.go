@@ -0,0 +1,77 @@
+// Package toolchain defines the pluggable interface between the compile
+// pipeline and the FPGA vendor toolchains it can target. Each Toolchain
+// implementation knows its own synthesis command, constraint file
+// convention, and the ordered place-and-route/packing steps needed to
+// turn a synthesized netlist into a loadable bitstream; the pipeline
+// itself stays toolchain-agnostic.
+package toolchain
+
+import "errors"
+
+// ErrNotImplemented is returned by Steps for toolchains that are
+// registered (so they appear in /api/targets) but whose compile flow
+// hasn't been wired up yet.
+var ErrNotImplemented = errors.New("toolchain: not implemented")
+
+// Step is a single command to run as part of compiling for a Target, in
+// the same work directory the yosys synthesis step ran in.
+type Step struct {
+	Command  string
+	Args     []string
+	Artifact string // expected output artifact relative to the work dir, if any
+}
+
+// Toolchain describes how to compile a yosys-synthesized design down to a
+// loadable bitstream for one FPGA target.
+type Toolchain interface {
+	// Name identifies the toolchain. It is used as CompileRequest.Target,
+	// as the "target" label on metrics, and in /api/targets.
+	Name() string
+
+	// ConstraintFile is the name of the pin/region constraint file this
+	// toolchain expects in the work directory (PCF, LPF, or CST).
+	ConstraintFile() string
+
+	// ConstraintSourcePath is where the board's pin/region constraint
+	// file for this toolchain is deployed on the compiler host; its
+	// contents are copied into the work directory as ConstraintFile
+	// before place-and-route. Each target has its own board and pin
+	// assignment, so this is never derived from another target's path.
+	ConstraintSourcePath() string
+
+	// BitstreamFile is the name of the final bitstream artifact this
+	// toolchain produces in the work directory.
+	BitstreamFile() string
+
+	// SynthCommand returns the yosys `-p` script for this target given the
+	// name of the top module to synthesize.
+	SynthCommand(topModule string) string
+
+	// Steps returns the ordered place-and-route/packing commands to run
+	// after yosys, given the clock frequency (MHz) and seed from the
+	// CompileRequest and any target-specific options. It returns
+	// ErrNotImplemented if the toolchain isn't runnable yet.
+	Steps(freq, seed int, opts map[string]string) ([]Step, error)
+
+	// RequiredOptions lists the TargetOptions keys this toolchain expects
+	// (e.g. "package", "device"), surfaced via /api/targets.
+	RequiredOptions() []string
+}
+
+// Registry maps target identifiers, as used in CompileRequest.Target, to
+// their Toolchain implementation. It is populated by the init() function
+// of each toolchain's file.
+var Registry = map[string]Toolchain{}
+
+func register(t Toolchain) {
+	Registry[t.Name()] = t
+}
+
+// Names returns the registered target identifiers.
+func Names() []string {
+	names := make([]string, 0, len(Registry))
+	for name := range Registry {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package toolchain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestICE40UP5KStepsDefaultsPackage(t *testing.T) {
+	steps, err := ICE40UP5K{}.Steps(24, 7, map[string]string{})
+	if err != nil {
+		t.Fatalf("Steps: %v", err)
+	}
+
+	want := []Step{
+		{
+			Command: "nextpnr-ice40",
+			Args: []string{
+				"--pcf-allow-unconstrained",
+				"--seed", "7",
+				"--freq", "24",
+				"--package", "sg48",
+				"--up5k",
+				"--asc", "output.asc",
+				"--pcf", "fpga.pcf",
+				"--json", "output.json",
+			},
+		},
+		{Command: "icepack", Args: []string{"output.asc", "output.bin"}, Artifact: "output.bin"},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("Steps() = %+v, want %+v", steps, want)
+	}
+}
+
+func TestICE40UP5KStepsHonorsExplicitPackage(t *testing.T) {
+	steps, err := ICE40UP5K{}.Steps(24, 7, map[string]string{"package": "uwg30"})
+	if err != nil {
+		t.Fatalf("Steps: %v", err)
+	}
+	if steps[0].Args[6] != "uwg30" {
+		t.Errorf("--package arg = %q, want uwg30", steps[0].Args[6])
+	}
+}
+
+func TestICE40UP5KRequiredOptionsIsEmpty(t *testing.T) {
+	if opts := (ICE40UP5K{}).RequiredOptions(); len(opts) != 0 {
+		t.Errorf("RequiredOptions() = %v, want none (package has a default)", opts)
+	}
+}
+
+func TestECP5StepsUsesDeviceAndPackageOptions(t *testing.T) {
+	steps, err := ECP5{}.Steps(12, 3, map[string]string{"device": "LFE5U-25F", "package": "CABGA381"})
+	if err != nil {
+		t.Fatalf("Steps: %v", err)
+	}
+
+	want := []Step{
+		{
+			Command: "nextpnr-ecp5",
+			Args: []string{
+				"--device", "LFE5U-25F",
+				"--package", "CABGA381",
+				"--seed", "3",
+				"--freq", "12",
+				"--lpf", "fpga.lpf",
+				"--json", "output.json",
+				"--textcfg", "output.config",
+			},
+		},
+		{Command: "ecppack", Args: []string{"output.config", "output.bit"}, Artifact: "output.bit"},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("Steps() = %+v, want %+v", steps, want)
+	}
+}
+
+func TestGowinLittleBeeStepsUsesDeviceOption(t *testing.T) {
+	steps, err := GowinLittleBee{}.Steps(12, 3, map[string]string{"device": "GW1N-LV1QN48C6/I5"})
+	if err != nil {
+		t.Fatalf("Steps: %v", err)
+	}
+
+	want := []Step{
+		{
+			Command: "nextpnr-himbaechel",
+			Args: []string{
+				"--device", "GW1N-LV1QN48C6/I5",
+				"--vopt", "cst=fpga.cst",
+				"--json", "output.json",
+				"--write", "output.pnr.json",
+			},
+		},
+		{
+			Command:  "gowin_pack",
+			Args:     []string{"-d", "GW1N-LV1QN48C6/I5", "-o", "output.fs", "output.pnr.json"},
+			Artifact: "output.fs",
+		},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("Steps() = %+v, want %+v", steps, want)
+	}
+}
+
+func TestXilinxVivadoStepsNotImplemented(t *testing.T) {
+	_, err := XilinxVivado{}.Steps(12, 3, map[string]string{"part": "xc7a35tcpg236-1"})
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Steps() err = %v, want ErrNotImplemented", err)
+	}
+}
+
+func TestRegistryContainsAllTargets(t *testing.T) {
+	want := []string{"ice40up5k", "ecp5", "gowin-littlebee", "xilinx-vivado"}
+	for _, name := range want {
+		if _, ok := Registry[name]; !ok {
+			t.Errorf("Registry is missing target %q", name)
+		}
+	}
+}
+
+func TestRequiredOptions(t *testing.T) {
+	cases := []struct {
+		tc   Toolchain
+		want []string
+	}{
+		{ICE40UP5K{}, nil},
+		{ECP5{}, []string{"device", "package"}},
+		{GowinLittleBee{}, []string{"device"}},
+		{XilinxVivado{}, []string{"part"}},
+	}
+	for _, c := range cases {
+		if got := c.tc.RequiredOptions(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s.RequiredOptions() = %v, want %v", c.tc.Name(), got, c.want)
+		}
+	}
+}
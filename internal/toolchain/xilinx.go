@@ -0,0 +1,24 @@
+package toolchain
+
+// XilinxVivado is a placeholder for a future Vivado-based flow targeting
+// Xilinx 7-series and UltraScale parts. It is registered so it's visible
+// via /api/targets, but Steps refuses to run: Vivado's TCL-driven
+// synthesis and implementation don't fit the yosys+nextpnr pipeline the
+// other toolchains share and will need a dedicated runner.
+type XilinxVivado struct{}
+
+func init() { register(XilinxVivado{}) }
+
+func (XilinxVivado) Name() string                 { return "xilinx-vivado" }
+func (XilinxVivado) ConstraintFile() string       { return "fpga.xdc" }
+func (XilinxVivado) ConstraintSourcePath() string { return "/app/verilog/tt_fpga_xilinx.xdc" }
+func (XilinxVivado) BitstreamFile() string        { return "output.bit" }
+func (XilinxVivado) RequiredOptions() []string    { return []string{"part"} }
+
+func (XilinxVivado) SynthCommand(topModule string) string {
+	return "synth_xilinx -top " + topModule + " -json output.json"
+}
+
+func (XilinxVivado) Steps(freq, seed int, opts map[string]string) ([]Step, error) {
+	return nil, ErrNotImplemented
+}
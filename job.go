@@ -0,0 +1,186 @@
+// job.go implements the asynchronous compilation job subsystem: jobs are
+// enqueued by the API handlers, executed by a bounded worker pool, and their
+// StreamMessage history is buffered so that SSE subscribers can reconnect
+// mid-compilation without losing output.
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobStatus is the lifecycle state of a compilation job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job tracks a single compilation request from enqueue through completion.
+// ID, Request, and CreatedAt are set once in newJob and never mutated
+// afterwards, so they're safe to read without locking; every other field
+// is mutable and must go through Job's mutex, via the accessor methods
+// below — startedAt/endedAt included, since the worker goroutine that
+// runs the job and the HTTP handler goroutines serving GET /api/jobs/{id}
+// read and write them concurrently.
+type Job struct {
+	ID        string
+	Request   CompileRequest
+	CreatedAt time.Time
+
+	mu        sync.Mutex
+	status    JobStatus
+	errMsg    string
+	startedAt time.Time
+	endedAt   time.Time
+	history   []StreamMessage
+	subs      map[chan StreamMessage]struct{}
+	done      chan struct{}
+	bitstream []byte
+}
+
+func newJob(req CompileRequest) *Job {
+	return &Job{
+		ID:        uuid.New().String(),
+		Request:   req,
+		CreatedAt: time.Now(),
+		status:    JobQueued,
+		subs:      make(map[chan StreamMessage]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// newJobFromRecord reconstructs a Job from a jobRecord persisted by
+// JobStore before a restart. Its replay history is empty — jobstore.go
+// deliberately doesn't persist it, so a client reconnecting after a
+// restart sees the recovered status rather than stale replayed output —
+// and its status/errMsg reflect whatever the record last had saved; it's
+// the caller's responsibility to update them once the job's fate after
+// the restart is decided (see JobQueue's recovery in queue.go).
+func newJobFromRecord(rec *jobRecord) *Job {
+	j := &Job{
+		ID:        rec.ID,
+		Request:   rec.Request,
+		CreatedAt: rec.CreatedAt,
+		status:    rec.Status,
+		errMsg:    rec.ErrMsg,
+		startedAt: rec.StartedAt,
+		endedAt:   rec.EndedAt,
+		subs:      make(map[chan StreamMessage]struct{}),
+		done:      make(chan struct{}),
+	}
+	if rec.Status == JobSucceeded || rec.Status == JobFailed {
+		close(j.done)
+	}
+	return j
+}
+
+// Status returns the job's current lifecycle state and, if it failed, the
+// error message.
+func (j *Job) Status() (JobStatus, string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status, j.errMsg
+}
+
+// Timing returns the job's start and end times; either is the zero
+// time.Time if the job hasn't reached that stage yet.
+func (j *Job) Timing() (startedAt, endedAt time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.startedAt, j.endedAt
+}
+
+// start records the job's start time and returns it, for use as the base
+// time for the compilation's transcript and cache-replay timestamps.
+func (j *Job) start() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.startedAt = time.Now()
+	return j.startedAt
+}
+
+// end records the job's end time and returns the (startedAt, endedAt)
+// pair, for computing its total duration.
+func (j *Job) end() (startedAt, endedAt time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.endedAt = time.Now()
+	return j.startedAt, j.endedAt
+}
+
+func (j *Job) setStatus(status JobStatus) {
+	j.mu.Lock()
+	j.status = status
+	j.mu.Unlock()
+}
+
+func (j *Job) fail(errMsg string) {
+	j.mu.Lock()
+	j.status = JobFailed
+	j.errMsg = errMsg
+	j.mu.Unlock()
+}
+
+// Bitstream returns the compiled bitstream, if the job has succeeded.
+func (j *Job) Bitstream() ([]byte, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.bitstream, j.bitstream != nil
+}
+
+// publish implements messageSink. It appends msg to the job's replay
+// history and fans it out to any currently subscribed SSE clients.
+func (j *Job) publish(msg StreamMessage) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.history = append(j.history, msg)
+	for ch := range j.subs {
+		select {
+		case ch <- msg:
+		default:
+			// Slow subscriber; drop rather than block the worker. It will
+			// still see the full history on reconnect.
+		}
+	}
+}
+
+// subscribe registers a new SSE subscriber and returns the buffered history
+// replayed so far plus a channel for messages published from now on. The
+// returned unsubscribe func must be called when the client disconnects.
+func (j *Job) subscribe() (history []StreamMessage, ch chan StreamMessage, unsubscribe func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	history = append([]StreamMessage(nil), j.history...)
+	ch = make(chan StreamMessage, 64)
+	j.subs[ch] = struct{}{}
+
+	unsubscribe = func() {
+		j.mu.Lock()
+		delete(j.subs, ch)
+		j.mu.Unlock()
+	}
+	return history, ch, unsubscribe
+}
+
+// Done returns a channel that is closed once the job has finished running.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+func (j *Job) close() {
+	close(j.done)
+}
+
+// messageSink receives StreamMessage events produced while a compilation
+// runs. Job implements it directly; teeSink and captureSink (sink.go)
+// wrap another sink to also record or capture what passes through.
+type messageSink interface {
+	publish(msg StreamMessage)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestJobTimingConcurrentAccess reproduces the access pattern that races
+// in production: a worker goroutine calling start()/end() (queue.go) while
+// another goroutine concurrently reads Timing() (main.go's handleJobStatus
+// polling GET /api/jobs/{id}). Run with -race to catch a regression.
+func TestJobTimingConcurrentAccess(t *testing.T) {
+	job := newJob(CompileRequest{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		job.start()
+		job.end()
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			job.Timing()
+		}
+	}()
+
+	wg.Wait()
+
+	startedAt, endedAt := job.Timing()
+	if startedAt.IsZero() || endedAt.IsZero() {
+		t.Fatal("expected both startedAt and endedAt to be set after start()/end()")
+	}
+}
@@ -0,0 +1,209 @@
+// jobstore.go implements the pluggable persistence for JobQueue's own job
+// metadata (distinct from ArtifactStore, which persists the resulting
+// bitstream, and CompilationCache, which persists a compiled result keyed
+// by content hash): without it, a server restart drops every queued and
+// in-flight job, and previously issued jobIds 404 forever even though the
+// artifact and cache stores survive just fine.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// jobRecord is the persisted snapshot of a Job: enough to recover its
+// status after a restart and resubmit work that hadn't started yet.
+// Per-run state that only matters to currently-connected SSE subscribers
+// (history, subs, the done channel) isn't persisted — a client
+// reconnecting after a server restart is expected to see the recovered
+// status rather than replayed output.
+type jobRecord struct {
+	ID        string         `json:"id"`
+	Request   CompileRequest `json:"request"`
+	Status    JobStatus      `json:"status"`
+	ErrMsg    string         `json:"errMsg,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	StartedAt time.Time      `json:"startedAt,omitempty"`
+	EndedAt   time.Time      `json:"endedAt,omitempty"`
+}
+
+// JobStore persists job metadata so JobQueue can recover queued and
+// in-flight jobs across a restart.
+type JobStore interface {
+	Save(rec *jobRecord) error
+	Delete(id string) error
+	LoadAll() ([]*jobRecord, error)
+}
+
+// newJobStore selects a JobStore implementation based on the
+// JOB_QUEUE_STORE environment variable ("fs", the default, or "s3").
+func newJobStore() (JobStore, error) {
+	switch os.Getenv("JOB_QUEUE_STORE") {
+	case "s3":
+		return newS3JobStore()
+	case "", "fs":
+		return newFSJobStore(envOr("JOB_QUEUE_STORE_DIR", "/var/lib/fpga-compiler/jobs"))
+	default:
+		return nil, fmt.Errorf("unknown JOB_QUEUE_STORE %q", os.Getenv("JOB_QUEUE_STORE"))
+	}
+}
+
+// FSJobStore persists each job as a JSON file under a root directory.
+type FSJobStore struct {
+	root string
+}
+
+func newFSJobStore(root string) (*FSJobStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job queue store root %s: %w", root, err)
+	}
+	return &FSJobStore{root: root}, nil
+}
+
+func (s *FSJobStore) path(id string) string {
+	return filepath.Join(s.root, id+".json")
+}
+
+func (s *FSJobStore) Save(rec *jobRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(rec.ID), data, 0644)
+}
+
+func (s *FSJobStore) Delete(id string) error {
+	err := os.Remove(s.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FSJobStore) LoadAll() ([]*jobRecord, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var recs []*jobRecord
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.root, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var rec jobRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			continue
+		}
+		recs = append(recs, &rec)
+	}
+	return recs, nil
+}
+
+// S3JobStore persists each job as an object in an S3-compatible bucket,
+// for deployments that run more than one server instance behind a load
+// balancer: any instance can recover any job's metadata, not just the one
+// that originally accepted it.
+type S3JobStore struct {
+	client *minio.Client
+	bucket string
+}
+
+func newS3JobStore() (*S3JobStore, error) {
+	endpoint := os.Getenv("JOB_QUEUE_MINIO_ENDPOINT")
+	if endpoint == "" {
+		return nil, fmt.Errorf("JOB_QUEUE_MINIO_ENDPOINT is required when JOB_QUEUE_STORE=s3")
+	}
+	bucket := os.Getenv("JOB_QUEUE_MINIO_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("JOB_QUEUE_MINIO_BUCKET is required when JOB_QUEUE_STORE=s3")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("JOB_QUEUE_MINIO_ACCESS_KEY"), os.Getenv("JOB_QUEUE_MINIO_SECRET_KEY"), ""),
+		Secure: envOr("JOB_QUEUE_MINIO_USE_SSL", "true") == "true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+	}
+
+	return &S3JobStore{client: client, bucket: bucket}, nil
+}
+
+func (s *S3JobStore) key(id string) string { return id + ".json" }
+
+func (s *S3JobStore) Save(rec *jobRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	_, err = s.client.PutObject(ctx, s.bucket, s.key(rec.ID), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	})
+	return err
+}
+
+func (s *S3JobStore) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	return s.client.RemoveObject(ctx, s.bucket, s.key(id), minio.RemoveObjectOptions{})
+}
+
+func (s *S3JobStore) LoadAll() ([]*jobRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+
+	var recs []*jobRecord
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		getCtx, getCancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+		o, err := s.client.GetObject(getCtx, s.bucket, obj.Key, minio.GetObjectOptions{})
+		if err != nil {
+			getCancel()
+			continue
+		}
+		buf := new(bytes.Buffer)
+		_, err = buf.ReadFrom(o)
+		o.Close()
+		getCancel()
+		if err != nil {
+			continue
+		}
+
+		var rec jobRecord
+		if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+			continue
+		}
+		recs = append(recs, &rec)
+	}
+	return recs, nil
+}
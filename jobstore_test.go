@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFSJobStoreSaveLoadDelete(t *testing.T) {
+	s, err := newFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSJobStore: %v", err)
+	}
+
+	rec := &jobRecord{
+		ID:        "abc",
+		Request:   CompileRequest{TopModule: "top"},
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Save(rec); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	recs, err := s.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(recs) != 1 || recs[0].ID != "abc" || recs[0].Status != JobQueued {
+		t.Fatalf("LoadAll = %+v, want one record with id abc status queued", recs)
+	}
+
+	if err := s.Delete("abc"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	recs, err = s.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll after delete: %v", err)
+	}
+	if len(recs) != 0 {
+		t.Fatalf("LoadAll after delete = %+v, want none", recs)
+	}
+}
+
+func TestFSJobStoreDeleteMissingIsNotError(t *testing.T) {
+	s, err := newFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSJobStore: %v", err)
+	}
+	if err := s.Delete("does-not-exist"); err != nil {
+		t.Errorf("Delete of a missing record should be a no-op, got: %v", err)
+	}
+}
+
+func TestFSJobStoreLoadAllIgnoresUnrelatedFiles(t *testing.T) {
+	root := t.TempDir()
+	s, err := newFSJobStore(root)
+	if err != nil {
+		t.Fatalf("newFSJobStore: %v", err)
+	}
+	if err := s.Save(&jobRecord{ID: "real", Status: JobSucceeded, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("not a job"), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "subdir.json"), 0755); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	recs, err := s.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(recs) != 1 || recs[0].ID != "real" {
+		t.Fatalf("LoadAll = %+v, want only the real record", recs)
+	}
+}
@@ -5,41 +5,66 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/TinyTapeout/tt-fpga-compiler/internal/toolchain"
 )
 
 const (
-	// CompilationTimeout is the maximum time allowed for a compilation request
+	// CompilationTimeout is the maximum time allowed for a compilation request.
 	CompilationTimeout = 120 * time.Second
+
+	// artifactOpTimeout bounds individual artifact store operations (S3
+	// round-trips or filesystem I/O) independent of CompilationTimeout.
+	artifactOpTimeout = 30 * time.Second
+
+	// defaultJobTTL is how long a finished job's status and artifact stay
+	// available after completion before the GC reaps them.
+	defaultJobTTL = time.Hour
+
+	// defaultWorkerPoolSize is the number of concurrent compilations run
+	// when WORKER_POOL_SIZE is unset.
+	defaultWorkerPoolSize = 4
 )
 
+// defaultTarget is used when CompileRequest.Target is empty, preserving
+// the server's original ice40 up5k behavior.
+const defaultTarget = "ice40up5k"
+
 type CompileRequest struct {
-	Sources   map[string]string `json:"sources"`
-	TopModule string            `json:"topModule"`
-	Freq      *int              `json:"freq,omitempty"`
-	Seed      *int              `json:"seed,omitempty"`
+	Sources       map[string]string `json:"sources"`
+	TopModule     string            `json:"topModule"`
+	Freq          *int              `json:"freq,omitempty"`
+	Seed          *int              `json:"seed,omitempty"`
+	Target        string            `json:"target,omitempty"`
+	TargetOptions map[string]string `json:"targetOptions,omitempty"`
 }
 
 type StreamMessage struct {
-	Type    string   `json:"type"` // "command", "stdout", "stderr", "error", "success"
+	Type    string   `json:"type"` // "command", "stdout", "stderr", "error", "oom", "rlimit_violation", "report", "success"
 	Command string   `json:"command,omitempty"`
 	Args    []string `json:"args,omitempty"`
 	Data    string   `json:"data,omitempty"`
 	Message string   `json:"message,omitempty"`
+
+	// Timestamp is only set when this message is replayed from the
+	// compilation cache: it's synthesized from the job's start time plus
+	// the message's recorded offset, since a cache hit has no live wall
+	// clock of its own to report.
+	Timestamp time.Time `json:"timestamp,omitempty"`
 }
 
 var (
@@ -72,7 +97,47 @@ var (
 			Help:    "Duration of individual FPGA toolchain command execution in seconds",
 			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10), // 0.1s, 0.2s, 0.4s, ... up to ~51s
 		},
-		[]string{"command"}, // "yosys", "nextpnr-ice40", "icepack"
+		[]string{"command", "target"}, // command: "yosys", "nextpnr-ice40", "icepack", ...; target: e.g. "ice40up5k"
+	)
+
+	queueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fpga_job_queue_depth",
+			Help: "Number of compilation jobs waiting for a free worker",
+		},
+	)
+
+	jobWaitDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "fpga_job_wait_duration_seconds",
+			Help:    "Time a job spent queued before a worker picked it up",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+		},
+	)
+
+	artifactSizeBytes = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "fpga_compilation_artifact_size_bytes",
+			Help:    "Size of compiled bitstream artifacts in bytes",
+			Buckets: prometheus.ExponentialBuckets(1024, 2, 12), // 1KiB ... 2MiB
+		},
+	)
+
+	compilationCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fpga_compilation_cache_hits_total",
+			Help: "Total number of compilation cache lookups",
+		},
+		[]string{"outcome"}, // "hit" or "miss"
+	)
+
+	achievedFmaxMHz = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "fpga_achieved_fmax_mhz",
+			Help:    "Achieved max frequency (MHz) reported by nextpnr for each clock",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10), // 1MHz ... ~512MHz
+		},
+		[]string{"target", "clock"},
 	)
 )
 
@@ -81,10 +146,103 @@ func init() {
 	prometheus.MustRegister(compilationDuration)
 	prometheus.MustRegister(compilationInProgress)
 	prometheus.MustRegister(commandExecutionDuration)
+	prometheus.MustRegister(queueDepth)
+	prometheus.MustRegister(jobWaitDuration)
+	prometheus.MustRegister(artifactSizeBytes)
+	prometheus.MustRegister(compilationCacheHits)
+	prometheus.MustRegister(achievedFmaxMHz)
 }
 
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid %s %q", key, v)
+	}
+	return n
+}
+
+func envInt64(key string, fallback int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Fatalf("invalid %s %q", key, v)
+	}
+	return n
+}
+
+// executor runs toolchain commands; it defaults to DirectExecutor and is
+// replaced in main() if EXECUTOR=sandbox is set.
+var executor Executor = DirectExecutor{}
+
 func main() {
-	http.HandleFunc("/api/compile", loggingMiddleware(corsMiddleware(handleCompile)))
+	// When re-invoked by SandboxExecutor from inside its sandbox, this
+	// process applies rlimits and execve's the real toolchain command
+	// instead of ever reaching the server below.
+	maybeReexec()
+
+	if envOr("EXECUTOR", "direct") == "sandbox" {
+		sandbox, err := newSandboxExecutor()
+		if err != nil {
+			log.Fatalf("failed to initialize sandbox executor: %v", err)
+		}
+		executor = sandbox
+	}
+
+	store, err := newArtifactStore()
+	if err != nil {
+		log.Fatalf("failed to initialize artifact store: %v", err)
+	}
+
+	cache, err := newCompilationCache()
+	if err != nil {
+		log.Fatalf("failed to initialize compilation cache: %v", err)
+	}
+
+	jobStore, err := newJobStore()
+	if err != nil {
+		log.Fatalf("failed to initialize job queue store: %v", err)
+	}
+
+	toolchainVersions := probeToolchainVersions()
+
+	workers := defaultWorkerPoolSize
+	if v := os.Getenv("WORKER_POOL_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid WORKER_POOL_SIZE %q", v)
+		}
+		workers = n
+	}
+
+	ttl := defaultJobTTL
+	if v := os.Getenv("JOB_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid JOB_TTL %q: %v", v, err)
+		}
+		ttl = d
+	}
+
+	queue := NewJobQueue(workers, ttl, store, cache, jobStore, toolchainVersions)
+	api := &apiServer{queue: queue, store: store}
+
+	http.HandleFunc("/api/jobs", loggingMiddleware(corsMiddleware(api.handleCreateJob)))
+	http.HandleFunc("/api/jobs/", loggingMiddleware(corsMiddleware(api.handleJobSubroute)))
+	http.HandleFunc("/api/targets", loggingMiddleware(corsMiddleware(handleListTargets)))
 	http.HandleFunc("/health", loggingMiddleware(handleHealth))
 	http.Handle("/metrics", promhttp.Handler())
 
@@ -93,7 +251,7 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
+	log.Printf("Server starting on port %s (workers=%d, job TTL=%s)", port, workers, ttl)
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
 		log.Fatal(err)
 	}
@@ -140,7 +298,7 @@ func loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -157,7 +315,46 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func handleCompile(w http.ResponseWriter, r *http.Request) {
+// targetInfo describes one compiled-in toolchain for /api/targets.
+type targetInfo struct {
+	Target          string   `json:"target"`
+	RequiredOptions []string `json:"requiredOptions"`
+}
+
+// handleListTargets implements GET /api/targets: the compiled-in
+// toolchains and the TargetOptions keys each one expects.
+func handleListTargets(w http.ResponseWriter, r *http.Request) {
+	targets := make([]targetInfo, 0, len(toolchain.Registry))
+	for name, tc := range toolchain.Registry {
+		targets = append(targets, targetInfo{Target: name, RequiredOptions: tc.RequiredOptions()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// validateTargetOptions checks that opts has a non-empty value for every
+// key tc.RequiredOptions() lists, so a missing target option (e.g.
+// device/package for ECP5, device for Gowin) fails the request with a clean 400
+// instead of reaching the toolchain as an empty-string CLI argument.
+func validateTargetOptions(tc toolchain.Toolchain, opts map[string]string) error {
+	for _, key := range tc.RequiredOptions() {
+		if opts[key] == "" {
+			return fmt.Errorf("missing required targetOptions key %q for target %s", key, tc.Name())
+		}
+	}
+	return nil
+}
+
+// apiServer holds the shared state needed by the job API handlers.
+type apiServer struct {
+	queue *JobQueue
+	store ArtifactStore
+}
+
+// handleCreateJob implements POST /api/jobs: it enqueues a CompileRequest
+// and returns immediately with the job's id and initial status.
+func (a *apiServer) handleCreateJob(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -169,21 +366,90 @@ func handleCompile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), CompilationTimeout)
-	defer cancel()
+	if req.Target == "" {
+		req.Target = defaultTarget
+	}
+	tc, ok := toolchain.Registry[req.Target]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown target %q", req.Target), http.StatusBadRequest)
+		return
+	}
+	if err := validateTargetOptions(tc, req.TargetOptions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Start metrics tracking
-	startTime := time.Now()
 	compilationInProgress.Inc()
-	status := "error" // Default to error, set to success on completion
-	defer func() {
-		compilationInProgress.Dec()
-		compilationDuration.Observe(time.Since(startTime).Seconds())
-		compilationRequestsTotal.WithLabelValues(status).Inc()
-	}()
+	job := a.queue.Enqueue(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"jobId":  job.ID,
+		"status": string(JobQueued),
+	})
+}
+
+// handleJobSubroute dispatches GET /api/jobs/{id}, /api/jobs/{id}/events
+// and /api/jobs/{id}/bitstream. The stdlib mux used elsewhere in this
+// server doesn't support path parameters, so routing within a job's
+// subtree is done by hand.
+func (a *apiServer) handleJobSubroute(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	id := parts[0]
+	job, ok := a.queue.Get(id)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		a.handleJobStatus(w, r, job)
+	case len(parts) == 2 && parts[1] == "events":
+		a.handleJobEvents(w, r, job)
+	case len(parts) == 2 && parts[1] == "bitstream":
+		a.handleJobBitstream(w, r, job)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleJobStatus implements GET /api/jobs/{id}: status and timing.
+func (a *apiServer) handleJobStatus(w http.ResponseWriter, r *http.Request, job *Job) {
+	status, errMsg := job.Status()
+	startedAt, endedAt := job.Timing()
+
+	resp := map[string]interface{}{
+		"jobId":     job.ID,
+		"status":    status,
+		"createdAt": job.CreatedAt,
+	}
+	if !startedAt.IsZero() {
+		resp["startedAt"] = startedAt
+	}
+	if !endedAt.IsZero() {
+		resp["endedAt"] = endedAt
+		resp["durationSeconds"] = endedAt.Sub(startedAt).Seconds()
+	}
+	if errMsg != "" {
+		resp["error"] = errMsg
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
 
-	// Set up SSE
+// handleJobEvents implements GET /api/jobs/{id}/events: an SSE stream that
+// replays buffered history for a late subscriber, then forwards messages
+// as they're published, closing once the job finishes.
+func (a *apiServer) handleJobEvents(w http.ResponseWriter, r *http.Request, job *Job) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -195,11 +461,69 @@ func handleCompile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create temporary directory for this compilation
+	history, ch, unsubscribe := job.subscribe()
+	defer unsubscribe()
+
+	for _, msg := range history {
+		sendSSE(w, flusher, msg)
+	}
+
+	for {
+		select {
+		case msg := <-ch:
+			sendSSE(w, flusher, msg)
+		case <-job.Done():
+			// Drain any messages published between the last select and
+			// the job closing before returning.
+			for {
+				select {
+				case msg := <-ch:
+					sendSSE(w, flusher, msg)
+				default:
+					return
+				}
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleJobBitstream implements GET /api/jobs/{id}/bitstream: the raw
+// compiled .bin, not base64-wrapped.
+func (a *apiServer) handleJobBitstream(w http.ResponseWriter, r *http.Request, job *Job) {
+	status, _ := job.Status()
+	if status != JobSucceeded {
+		http.Error(w, fmt.Sprintf("Job is %s, bitstream not available", status), http.StatusConflict)
+		return
+	}
+
+	bitstream, err := a.store.Get(job.ID)
+	if err != nil {
+		http.Error(w, "Failed to read bitstream", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.ID+".bin"))
+	w.Write(bitstream)
+}
+
+// compile runs the full toolchain for req in a fresh work directory,
+// publishing progress to sink, and returns the resulting bitstream.
+func compile(ctx context.Context, sink messageSink, req CompileRequest) ([]byte, error) {
+	target := req.Target
+	if target == "" {
+		target = defaultTarget
+	}
+	tc, ok := toolchain.Registry[target]
+	if !ok {
+		return nil, fmt.Errorf("unknown target %q", target)
+	}
+
 	workDir := filepath.Join("/tmp", "fpga-compile-"+uuid.New().String())
 	if err := os.MkdirAll(workDir, 0755); err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: "Failed to create work directory"})
-		return
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
 	}
 	defer os.RemoveAll(workDir)
 
@@ -207,36 +531,31 @@ func handleCompile(w http.ResponseWriter, r *http.Request) {
 	for name, content := range req.Sources {
 		filePath := filepath.Join(workDir, name)
 		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-			sendSSE(w, flusher, StreamMessage{Type: "error", Message: fmt.Sprintf("Failed to write file %s", name)})
-			return
+			return nil, fmt.Errorf("failed to write file %s: %w", name, err)
 		}
 	}
 
-	// Load FPGA top verilog and PCF
+	// Load FPGA top verilog
 	fpgaTopPath := "/app/verilog/tt_fpga_top.v"
 	fpgaTopContent, err := os.ReadFile(fpgaTopPath)
 	if err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: "Failed to read FPGA top verilog"})
-		return
+		return nil, fmt.Errorf("failed to read FPGA top verilog: %w", err)
 	}
 
 	// Replace placeholder with actual top module
 	topVerilog := strings.Replace(string(fpgaTopContent), "__tt_um_placeholder", req.TopModule, -1)
 	if err := os.WriteFile(filepath.Join(workDir, "top.v"), []byte(topVerilog), 0644); err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: "Failed to write top.v"})
-		return
+		return nil, fmt.Errorf("failed to write top.v: %w", err)
 	}
 
-	// Copy PCF file
-	pcfPath := "/app/verilog/tt_fpga_fabricfox.pcf"
-	pcfContent, err := os.ReadFile(pcfPath)
+	// Copy this target's own board pin/region constraint file into the
+	// work directory under the name its toolchain expects.
+	constraintContent, err := os.ReadFile(tc.ConstraintSourcePath())
 	if err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: "Failed to read PCF file"})
-		return
+		return nil, fmt.Errorf("failed to read constraint file: %w", err)
 	}
-	if err := os.WriteFile(filepath.Join(workDir, "fpga.pcf"), pcfContent, 0644); err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: "Failed to write fpga.pcf"})
-		return
+	if err := os.WriteFile(filepath.Join(workDir, tc.ConstraintFile()), constraintContent, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", tc.ConstraintFile(), err)
 	}
 
 	// Run Yosys
@@ -248,16 +567,15 @@ func handleCompile(w http.ResponseWriter, r *http.Request) {
 	yosysArgs := []string{
 		"-l", "yosys.log",
 		"-DSYNTH",
-		"-p", "synth_ice40 -top tt_fpga_top -json output.json",
+		"-p", tc.SynthCommand("tt_fpga_top"),
 		"top.v",
 	}
 	yosysArgs = append(yosysArgs, sourceFiles...)
 
-	if !runCommand(ctx, w, flusher, workDir, "yosys", yosysArgs) {
-		return
+	if !runCommand(ctx, sink, workDir, target, "yosys", yosysArgs) {
+		return nil, fmt.Errorf("yosys failed")
 	}
 
-	// Run nextpnr-ice40
 	freq := 12
 	if req.Freq != nil {
 		freq = *req.Freq
@@ -267,100 +585,108 @@ func handleCompile(w http.ResponseWriter, r *http.Request) {
 		seed = *req.Seed
 	}
 
-	nextpnrArgs := []string{
-		"--pcf-allow-unconstrained",
-		"--seed", fmt.Sprintf("%d", seed),
-		"--freq", fmt.Sprintf("%d", freq),
-		"--package", "sg48",
-		"--up5k",
-		"--asc", "output.asc",
-		"--pcf", "fpga.pcf",
-		"--json", "output.json",
-	}
-
-	if !runCommand(ctx, w, flusher, workDir, "nextpnr-ice40", nextpnrArgs) {
-		return
+	steps, err := tc.Steps(freq, seed, req.TargetOptions)
+	if err != nil {
+		return nil, fmt.Errorf("target %s: %w", target, err)
 	}
 
-	// Run icepack
-	icepackArgs := []string{"output.asc", "output.bin"}
-	if !runCommand(ctx, w, flusher, workDir, "icepack", icepackArgs) {
-		return
+	var nextpnrLog strings.Builder
+	for _, step := range steps {
+		cmdSink := sink
+		if strings.HasPrefix(step.Command, "nextpnr") {
+			cmdSink = &captureSink{messageSink: sink, buf: &nextpnrLog}
+		}
+		if !runCommand(ctx, cmdSink, workDir, target, step.Command, step.Args) {
+			return nil, fmt.Errorf("%s failed", step.Command)
+		}
 	}
 
-	// Read and send the bitstream
-	bitstreamPath := filepath.Join(workDir, "output.bin")
+	// Read the bitstream
+	bitstreamPath := filepath.Join(workDir, tc.BitstreamFile())
 	bitstream, err := os.ReadFile(bitstreamPath)
 	if err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: "Failed to read bitstream"})
-		return
+		return nil, fmt.Errorf("failed to read bitstream: %w", err)
 	}
 
-	// Send success message with bitstream as base64
-	status = "success"
-	sendSSE(w, flusher, StreamMessage{
-		Type: "success",
-		Data: fmt.Sprintf("base64:%s", base64.StdEncoding.EncodeToString(bitstream)),
-	})
-}
+	publishBuildReport(sink, target, workDir, bitstream, nextpnrLog.String())
 
-func runCommand(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, workDir, command string, args []string) bool {
-	startTime := time.Now()
-	defer func() {
-		commandExecutionDuration.WithLabelValues(command).Observe(time.Since(startTime).Seconds())
-	}()
+	sink.publish(StreamMessage{Type: "success"})
+	return bitstream, nil
+}
 
-	sendSSE(w, flusher, StreamMessage{
-		Type:    "command",
-		Command: command,
-		Args:    args,
-	})
+// publishBuildReport parses yosys.log and nextpnrLog into a BuildReport
+// and publishes it as a "report" message; it also records each clock's
+// achieved Fmax so operators can track regressions across toolchain
+// updates. Report generation is best-effort and never fails the build:
+// the bitstream has already been produced by this point.
+func publishBuildReport(sink messageSink, target, workDir string, bitstream []byte, nextpnrLog string) {
+	yosysLog, _ := os.ReadFile(filepath.Join(workDir, "yosys.log"))
+	ascBytes := fileSizeOrZero(filepath.Join(workDir, "output.asc"))
+	jsonBytes := fileSizeOrZero(filepath.Join(workDir, "output.json"))
 
-	cmd := exec.CommandContext(ctx, command, args...)
-	cmd.Dir = workDir
+	report := buildReport(string(yosysLog), nextpnrLog, target, len(bitstream), ascBytes, jsonBytes)
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: fmt.Sprintf("Failed to create stdout pipe: %v", err)})
-		return false
+	for _, timing := range report.Timing {
+		achievedFmaxMHz.WithLabelValues(target, timing.ClockName).Observe(timing.AchievedMHz)
 	}
 
-	stderr, err := cmd.StderrPipe()
+	reportJSON, err := json.Marshal(report)
 	if err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: fmt.Sprintf("Failed to create stderr pipe: %v", err)})
-		return false
+		log.Printf("failed to marshal build report: %v", err)
+		return
 	}
+	sink.publish(StreamMessage{Type: "report", Data: string(reportJSON)})
+}
 
-	if err := cmd.Start(); err != nil {
-		sendSSE(w, flusher, StreamMessage{Type: "error", Message: fmt.Sprintf("Failed to start %s: %v", command, err)})
-		return false
+func fileSizeOrZero(path string) int {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
 	}
+	return int(info.Size())
+}
 
-	// Stream stdout
-	go streamOutput(w, flusher, stdout, "stdout")
+// runCommand executes command via the configured Executor (direct or
+// sandboxed), publishing a "command" message up front and, on failure, a
+// message whose Type distinguishes a timeout, an OOM kill, an rlimit
+// violation, or a generic tool failure.
+func runCommand(ctx context.Context, sink messageSink, workDir, target, command string, args []string) bool {
+	startTime := time.Now()
+	defer func() {
+		commandExecutionDuration.WithLabelValues(command, target).Observe(time.Since(startTime).Seconds())
+	}()
 
-	// Stream stderr
-	go streamOutput(w, flusher, stderr, "stderr")
+	sink.publish(StreamMessage{
+		Type:    "command",
+		Command: command,
+		Args:    args,
+	})
 
-	if err := cmd.Wait(); err != nil {
-		// Check if the error is due to context timeout
-		if ctx.Err() == context.DeadlineExceeded {
-			sendSSE(w, flusher, StreamMessage{Type: "error", Message: fmt.Sprintf("Compilation timeout: operation exceeded %v", CompilationTimeout)})
-		} else {
-			sendSSE(w, flusher, StreamMessage{Type: "error", Message: fmt.Sprintf("%s failed: %v", command, err)})
-		}
-		return false
+	result := executor.Run(ctx, sink, workDir, command, args)
+
+	switch {
+	case result.Ok():
+		return true
+	case ctx.Err() == context.DeadlineExceeded:
+		sink.publish(StreamMessage{Type: "error", Command: command, Message: fmt.Sprintf("Compilation timeout: operation exceeded %v", CompilationTimeout)})
+	case result.OOMKilled:
+		sink.publish(StreamMessage{Type: "oom", Command: command, Message: fmt.Sprintf("%s was killed for exceeding its memory limit", command)})
+	case result.RLimitViolation != "":
+		sink.publish(StreamMessage{Type: "rlimit_violation", Command: command, Message: fmt.Sprintf("%s exceeded its %s resource limit", command, result.RLimitViolation)})
+	case result.Err != nil:
+		sink.publish(StreamMessage{Type: "error", Command: command, Message: fmt.Sprintf("Failed to run %s: %v", command, result.Err)})
+	default:
+		sink.publish(StreamMessage{Type: "error", Command: command, Message: fmt.Sprintf("%s failed: exit code %d", command, result.ExitCode)})
 	}
-
-	return true
+	return false
 }
 
-func streamOutput(w http.ResponseWriter, flusher http.Flusher, reader io.Reader, stream string) {
+func streamOutput(sink messageSink, reader io.Reader, stream string) {
 	buf := make([]byte, 1024)
 	for {
 		n, err := reader.Read(buf)
 		if n > 0 {
-			sendSSE(w, flusher, StreamMessage{
+			sink.publish(StreamMessage{
 				Type: stream,
 				Data: string(buf[:n]),
 			})
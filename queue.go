@@ -0,0 +1,250 @@
+// queue.go implements the bounded worker pool that drains queued jobs,
+// the TTL-based garbage collector that evicts finished ones along with
+// their stored artifacts, and recovery of queued/in-flight jobs from
+// JobStore after a restart.
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// JobQueue owns the lifecycle of every Job: enqueueing, dispatching to a
+// fixed-size worker pool, and reaping completed jobs once they are older
+// than ttl.
+type JobQueue struct {
+	mu                sync.RWMutex
+	jobs              map[string]*Job
+	pending           chan *Job
+	ttl               time.Duration
+	store             ArtifactStore
+	cache             CompilationCache
+	jobStore          JobStore
+	toolchainVersions map[string]string
+}
+
+// NewJobQueue starts workers background goroutines to execute queued jobs
+// and a GC loop that evicts jobs (and their artifacts) ttl after they
+// finish. toolchainVersions is folded into each job's compilation cache
+// key so a toolchain upgrade invalidates existing entries. Before
+// returning, it recovers any jobs jobStore persisted before a previous
+// restart: still-queued jobs are safely resubmitted, and jobs that were
+// running are marked failed, since their toolchain subprocess died with
+// the old process and can't be resumed.
+func NewJobQueue(workers int, ttl time.Duration, store ArtifactStore, cache CompilationCache, jobStore JobStore, toolchainVersions map[string]string) *JobQueue {
+	q := &JobQueue{
+		jobs:              make(map[string]*Job),
+		pending:           make(chan *Job, 256),
+		ttl:               ttl,
+		store:             store,
+		cache:             cache,
+		jobStore:          jobStore,
+		toolchainVersions: toolchainVersions,
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	go q.gcLoop()
+	q.recover()
+	return q
+}
+
+// recover loads every jobRecord jobStore has persisted and restores it:
+// a job still JobQueued had no side effects yet, so it's safe to
+// re-enqueue as-is; a job that was JobRunning had its toolchain
+// subprocess die along with the previous process and can't be resumed,
+// so it's recorded as failed instead; anything already terminal
+// (JobSucceeded/JobFailed) is restored so its status and artifact (durable
+// in ArtifactStore independent of this recovery) stay reachable by id.
+func (q *JobQueue) recover() {
+	recs, err := q.jobStore.LoadAll()
+	if err != nil {
+		log.Printf("failed to load persisted jobs: %v", err)
+		return
+	}
+
+	for _, rec := range recs {
+		job := newJobFromRecord(rec)
+
+		q.mu.Lock()
+		q.jobs[job.ID] = job
+		q.mu.Unlock()
+
+		switch rec.Status {
+		case JobQueued:
+			compilationInProgress.Inc()
+			queueDepth.Inc()
+			q.pending <- job
+		case JobRunning:
+			job.end()
+			job.fail("job was still running when the server restarted")
+			job.close()
+			q.persist(job)
+		}
+	}
+}
+
+// persist saves job's current status/timing to jobStore, logging rather
+// than failing the caller if it can't — losing the ability to recover
+// one job across a future restart shouldn't fail the compilation itself.
+func (q *JobQueue) persist(job *Job) {
+	status, errMsg := job.Status()
+	startedAt, endedAt := job.Timing()
+	rec := &jobRecord{
+		ID:        job.ID,
+		Request:   job.Request,
+		Status:    status,
+		ErrMsg:    errMsg,
+		CreatedAt: job.CreatedAt,
+		StartedAt: startedAt,
+		EndedAt:   endedAt,
+	}
+	if err := q.jobStore.Save(rec); err != nil {
+		log.Printf("failed to persist job %s: %v", job.ID, err)
+	}
+}
+
+// Enqueue creates a new Job for req, makes it visible to Get, persists it
+// so it survives a restart before it's started, and hands it to the
+// worker pool.
+func (q *JobQueue) Enqueue(req CompileRequest) *Job {
+	job := newJob(req)
+
+	q.mu.Lock()
+	q.jobs[job.ID] = job
+	q.mu.Unlock()
+
+	q.persist(job)
+	queueDepth.Inc()
+	q.pending <- job
+	return job
+}
+
+// Get returns the job with the given id, if it is still known to the
+// queue (it may have been reaped after its TTL elapsed).
+func (q *JobQueue) Get(id string) (*Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.jobs[id]
+	return job, ok
+}
+
+func (q *JobQueue) worker() {
+	for job := range q.pending {
+		queueDepth.Dec()
+		q.run(job)
+	}
+}
+
+func (q *JobQueue) run(job *Job) {
+	jobWaitDuration.Observe(time.Since(job.CreatedAt).Seconds())
+
+	startedAt := job.start()
+	job.setStatus(JobRunning)
+	q.persist(job)
+
+	key := newCacheKey(job.Request, q.toolchainVersions)
+
+	if entry, ok := q.cache.Get(key); ok {
+		compilationCacheHits.WithLabelValues("hit").Inc()
+		replayCacheEntry(job, entry, startedAt)
+		q.finish(job, entry.Bitstream, nil)
+		return
+	}
+	compilationCacheHits.WithLabelValues("miss").Inc()
+
+	ctx, cancel := context.WithTimeout(context.Background(), CompilationTimeout)
+	defer cancel()
+
+	recorder := newTranscriptRecorder(startedAt)
+	bitstream, err := compile(ctx, teeSink{job: job, recorder: recorder}, job.Request)
+	if err == nil {
+		if cacheErr := q.cache.Put(key, &CacheEntry{Transcript: recorder.messages, Bitstream: bitstream}); cacheErr != nil {
+			log.Printf("failed to cache compilation result for job %s: %v", job.ID, cacheErr)
+		}
+	}
+
+	q.finish(job, bitstream, err)
+}
+
+// replayCacheEntry publishes a cached run's transcript to job, reusing
+// the original run's relative pacing to synthesize a Timestamp for each
+// message relative to startedAt rather than replaying it in real time.
+func replayCacheEntry(job *Job, entry *CacheEntry, startedAt time.Time) {
+	for _, cm := range entry.Transcript {
+		msg := cm.Msg
+		msg.Timestamp = startedAt.Add(cm.Elapsed)
+		job.publish(msg)
+	}
+}
+
+// finish records the outcome of a job run — from either a live
+// compilation or a cache replay — storing the bitstream and marking the
+// job succeeded, or marking it failed if err is set or storage fails. The
+// resulting status is persisted before returning, whichever branch it
+// came from.
+func (q *JobQueue) finish(job *Job, bitstream []byte, err error) {
+	defer q.persist(job)
+
+	startedAt, endedAt := job.end()
+	compilationDuration.Observe(endedAt.Sub(startedAt).Seconds())
+	compilationInProgress.Dec()
+
+	if err != nil {
+		job.fail(err.Error())
+		compilationRequestsTotal.WithLabelValues("error").Inc()
+		job.close()
+		return
+	}
+
+	if err := q.store.Put(job.ID, bitstream); err != nil {
+		job.fail("failed to store artifact: " + err.Error())
+		compilationRequestsTotal.WithLabelValues("error").Inc()
+		job.close()
+		return
+	}
+	artifactSizeBytes.Observe(float64(len(bitstream)))
+
+	job.mu.Lock()
+	job.bitstream = bitstream
+	job.mu.Unlock()
+
+	job.setStatus(JobSucceeded)
+	compilationRequestsTotal.WithLabelValues("success").Inc()
+	job.close()
+}
+
+func (q *JobQueue) gcLoop() {
+	interval := q.ttl / 2
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		q.gc()
+	}
+}
+
+func (q *JobQueue) gc() {
+	cutoff := time.Now().Add(-q.ttl)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, job := range q.jobs {
+		_, endedAt := job.Timing()
+		if endedAt.IsZero() || endedAt.After(cutoff) {
+			continue
+		}
+		if err := q.store.Delete(id); err != nil {
+			log.Printf("failed to delete artifact for job %s: %v", id, err)
+		}
+		if err := q.jobStore.Delete(id); err != nil {
+			log.Printf("failed to delete persisted job %s: %v", id, err)
+		}
+		delete(q.jobs, id)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newRecoveryTestQueue builds a JobQueue wired to jobStore without
+// starting NewJobQueue's worker/GC goroutines, so recover()'s effects can
+// be asserted directly.
+func newRecoveryTestQueue(jobStore JobStore) *JobQueue {
+	return &JobQueue{
+		jobs:     make(map[string]*Job),
+		pending:  make(chan *Job, 16),
+		jobStore: jobStore,
+	}
+}
+
+func TestJobQueueRecoverRequeuesQueuedJobs(t *testing.T) {
+	jobStore, err := newFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSJobStore: %v", err)
+	}
+	if err := jobStore.Save(&jobRecord{
+		ID:        "queued-job",
+		Request:   CompileRequest{TopModule: "top"},
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	q := newRecoveryTestQueue(jobStore)
+	q.recover()
+
+	job, ok := q.Get("queued-job")
+	if !ok {
+		t.Fatal("recovered queued job is not visible via Get")
+	}
+	if status, _ := job.Status(); status != JobQueued {
+		t.Errorf("status = %s, want queued", status)
+	}
+
+	select {
+	case pending := <-q.pending:
+		if pending.ID != "queued-job" {
+			t.Errorf("pending job id = %s, want queued-job", pending.ID)
+		}
+	default:
+		t.Error("recovered queued job was not pushed to the pending channel")
+	}
+}
+
+func TestJobQueueRecoverFailsRunningJobs(t *testing.T) {
+	jobStore, err := newFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSJobStore: %v", err)
+	}
+	if err := jobStore.Save(&jobRecord{
+		ID:        "running-job",
+		Request:   CompileRequest{TopModule: "top"},
+		Status:    JobRunning,
+		CreatedAt: time.Now(),
+		StartedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	q := newRecoveryTestQueue(jobStore)
+	q.recover()
+
+	job, ok := q.Get("running-job")
+	if !ok {
+		t.Fatal("recovered running job is not visible via Get")
+	}
+	status, errMsg := job.Status()
+	if status != JobFailed {
+		t.Errorf("status = %s, want failed (an in-flight job can't be resumed across a restart)", status)
+	}
+	if errMsg == "" {
+		t.Error("expected a non-empty error message explaining the restart-induced failure")
+	}
+
+	select {
+	case <-job.Done():
+	default:
+		t.Error("recovered running job should be marked done, not left hanging forever")
+	}
+
+	recs, err := jobStore.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(recs) != 1 || recs[0].Status != JobFailed {
+		t.Errorf("persisted records = %+v, want the running job re-saved as failed", recs)
+	}
+}
+
+func TestJobQueueRecoverRestoresTerminalJobs(t *testing.T) {
+	jobStore, err := newFSJobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSJobStore: %v", err)
+	}
+	if err := jobStore.Save(&jobRecord{
+		ID:        "done-job",
+		Request:   CompileRequest{TopModule: "top"},
+		Status:    JobSucceeded,
+		CreatedAt: time.Now(),
+		StartedAt: time.Now(),
+		EndedAt:   time.Now(),
+	}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	q := newRecoveryTestQueue(jobStore)
+	q.recover()
+
+	job, ok := q.Get("done-job")
+	if !ok {
+		t.Fatal("recovered succeeded job is not visible via Get")
+	}
+	if status, _ := job.Status(); status != JobSucceeded {
+		t.Errorf("status = %s, want succeeded", status)
+	}
+	select {
+	case <-job.Done():
+	default:
+		t.Error("a recovered terminal job should already be marked done")
+	}
+	select {
+	case <-q.pending:
+		t.Error("a terminal job should not be pushed back onto the pending channel")
+	default:
+	}
+}
@@ -0,0 +1,78 @@
+// reexec.go implements SandboxExecutor's rlimit-enforcement helper. Go's
+// os/exec has no hook to run code in the child between fork and exec, so
+// SandboxExecutor instead re-invokes this same binary with
+// sandboxReexecFlag from inside the sandbox; maybeReexec applies
+// RLIMIT_CPU/RLIMIT_AS/RLIMIT_FSIZE to itself and then execve's the real
+// toolchain command, replacing its own process image.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+const sandboxReexecFlag = "-sandbox-exec"
+
+const (
+	envRlimitCPU   = "SANDBOX_RLIMIT_CPU_SECONDS"
+	envRlimitAS    = "SANDBOX_RLIMIT_AS_BYTES"
+	envRlimitFSize = "SANDBOX_RLIMIT_FSIZE_BYTES"
+)
+
+// maybeReexec checks for sandboxReexecFlag as os.Args[1] and, if
+// present, never returns to main(): it applies rlimits and execve's the
+// wrapped toolchain command.
+func maybeReexec() {
+	if len(os.Args) < 3 || os.Args[1] != sandboxReexecFlag {
+		return
+	}
+
+	if err := applyRlimits(); err != nil {
+		fmt.Fprintf(os.Stderr, "fpga-compiler sandbox: failed to apply rlimits: %v\n", err)
+		os.Exit(1)
+	}
+
+	command := os.Args[2]
+	path, err := exec.LookPath(command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fpga-compiler sandbox: %s not found: %v\n", command, err)
+		os.Exit(127)
+	}
+
+	if err := syscall.Exec(path, os.Args[2:], os.Environ()); err != nil {
+		fmt.Fprintf(os.Stderr, "fpga-compiler sandbox: exec %s failed: %v\n", command, err)
+		os.Exit(1)
+	}
+}
+
+func applyRlimits() error {
+	if err := applyRlimit(syscall.RLIMIT_CPU, envRlimitCPU); err != nil {
+		return err
+	}
+	if err := applyRlimit(syscall.RLIMIT_AS, envRlimitAS); err != nil {
+		return err
+	}
+	if err := applyRlimit(syscall.RLIMIT_FSIZE, envRlimitFSize); err != nil {
+		return err
+	}
+	return nil
+}
+
+func applyRlimit(resource int, envKey string) error {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return nil
+	}
+	limit, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", envKey, err)
+	}
+	rlimit := syscall.Rlimit{Cur: limit, Max: limit}
+	if err := syscall.Setrlimit(resource, &rlimit); err != nil {
+		return fmt.Errorf("setrlimit %s: %w", envKey, err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// report.go post-processes a successful compilation's logs into a
+// structured BuildReport — cell utilization, achieved clock frequencies,
+// and tool warnings — so clients don't have to scrape raw stdout/stderr
+// text for information the toolchains already printed.
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ResourceUsage is how much of one FPGA resource a design consumed.
+type ResourceUsage struct {
+	Used      int     `json:"used"`
+	Available int     `json:"available"`
+	Percent   float64 `json:"percent"`
+}
+
+// Utilization is the cell-level resource usage reported for a build.
+type Utilization struct {
+	LUT4 ResourceUsage `json:"lut4"`
+	DFF  ResourceUsage `json:"dff"`
+	BRAM ResourceUsage `json:"bram"`
+	DSP  ResourceUsage `json:"dsp"`
+}
+
+// ClockTiming is the achieved vs. requested frequency for one clock
+// domain, as reported by nextpnr.
+type ClockTiming struct {
+	ClockName    string  `json:"clockName"`
+	AchievedMHz  float64 `json:"achievedMHz"`
+	RequestedMHz float64 `json:"requestedMHz"`
+	SlackNs      float64 `json:"slackNs"`
+}
+
+// Warning is one diagnostic emitted by a toolchain step.
+type Warning struct {
+	Tool     string `json:"tool"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// Artifacts records the size of each intermediate and final file a
+// build produced.
+type Artifacts struct {
+	BitstreamBytes int `json:"bitstreamBytes"`
+	AscBytes       int `json:"ascBytes"`
+	JSONBytes      int `json:"jsonBytes"`
+}
+
+// BuildReport summarizes a successful compilation for the client,
+// published as a "report" StreamMessage just before "success".
+type BuildReport struct {
+	Utilization Utilization   `json:"utilization"`
+	Timing      []ClockTiming `json:"timing"`
+	Warnings    []Warning     `json:"warnings"`
+	Artifacts   Artifacts     `json:"artifacts"`
+}
+
+var (
+	// yosysCellCountRE matches a yosys stat line, e.g.
+	// "     SB_LUT4                          600".
+	yosysCellCountRE = regexp.MustCompile(`^\s*(\w+)\s+(\d+)\s*$`)
+
+	// yosysWarningRE matches a yosys "Warning: ..." log line.
+	yosysWarningRE = regexp.MustCompile(`^Warning:\s*(.+)$`)
+
+	// nextpnrUtilizationRE matches a device utilization line, e.g.
+	// "Info: 	         ICESTORM_LC:  1234/ 5280    23%".
+	nextpnrUtilizationRE = regexp.MustCompile(`Info:\s*(\w+):\s*(\d+)/\s*(\d+)\s*(\d+)%`)
+
+	// nextpnrFmaxRE matches a max-frequency line, e.g.
+	// "Info: Max frequency for clock 'clk': 55.28 MHz (PASS at 12.00 MHz)".
+	nextpnrFmaxRE = regexp.MustCompile(`Info: Max frequency for clock '([^']+)': ([\d.]+) MHz \((?:PASS|FAIL) at ([\d.]+) MHz\)`)
+
+	// nextpnrUnconstrainedIORE matches an unconstrained-IO warning.
+	nextpnrUnconstrainedIORE = regexp.MustCompile(`^Warning:\s*(IO '.+' is unconstrained.*)$`)
+)
+
+// ice40CellUtilizationSupported is the only target whose yosys cell
+// names and nextpnr utilization-line device names this package knows:
+// SB_LUT4/SB_DFF*/SB_RAM40_4K/SB_MAC16 and ICESTORM_LC/ICESTORM_RAM/
+// SB_MAC16 are specific to synth_ice40 and nextpnr-ice40. ECP5 and
+// Gowin use entirely different cell and device names (TRELLIS_*,
+// DP16KD, MULT18X18D for ECP5; Himbaechel's own set for Gowin) that
+// haven't been verified against real tool output, so reporting
+// utilization for them would risk the same silently-wrong-zeros bug
+// this package already shipped once for DSP; gate utilization to ICE40
+// until those tables are added and verified.
+const ice40CellUtilizationSupported = "ice40up5k"
+
+// buildReport parses a yosys log and nextpnr's combined stdout/stderr
+// into a BuildReport for target, given the final bitstream's size.
+// Cell/device names in the utilization block are target-specific
+// (see ice40CellUtilizationSupported); for any other target,
+// Utilization is left zeroed and a warning explains why.
+func buildReport(yosysLog, nextpnrLog, target string, bitstreamBytes, ascBytes, jsonBytes int) BuildReport {
+	cellCounts := make(map[string]int)
+	var warnings []Warning
+
+	for _, line := range strings.Split(yosysLog, "\n") {
+		if m := yosysCellCountRE.FindStringSubmatch(line); m != nil {
+			cellCounts[m[1]] += atoiOr0(m[2])
+		}
+		if m := yosysWarningRE.FindStringSubmatch(line); m != nil {
+			warnings = append(warnings, Warning{Tool: "yosys", Severity: "warning", Message: m[1]})
+		}
+	}
+
+	var utilization Utilization
+	if target == ice40CellUtilizationSupported {
+		utilization = Utilization{
+			LUT4: ResourceUsage{Used: cellCounts["SB_LUT4"]},
+			DFF:  ResourceUsage{Used: sumCellCounts(cellCounts, "SB_DFF")},
+			BRAM: ResourceUsage{Used: cellCounts["SB_RAM40_4K"]},
+			DSP:  ResourceUsage{Used: cellCounts["SB_MAC16"]},
+		}
+	} else {
+		warnings = append(warnings, Warning{
+			Tool:     "fpga-compiler",
+			Severity: "info",
+			Message:  fmt.Sprintf("cell utilization reporting is not yet implemented for target %q", target),
+		})
+	}
+
+	var timing []ClockTiming
+	for _, line := range strings.Split(nextpnrLog, "\n") {
+		if m := nextpnrUtilizationRE.FindStringSubmatch(line); m != nil && target == ice40CellUtilizationSupported {
+			used, available := atoiOr0(m[2]), atoiOr0(m[3])
+			percent, _ := strconv.ParseFloat(m[4], 64)
+			applyNextpnrUtilization(&utilization, m[1], used, available, percent)
+		}
+		if m := nextpnrFmaxRE.FindStringSubmatch(line); m != nil {
+			achieved, _ := strconv.ParseFloat(m[2], 64)
+			requested, _ := strconv.ParseFloat(m[3], 64)
+			timing = append(timing, ClockTiming{
+				ClockName:    m[1],
+				AchievedMHz:  achieved,
+				RequestedMHz: requested,
+				SlackNs:      1000/requested - 1000/achieved,
+			})
+		}
+		if m := nextpnrUnconstrainedIORE.FindStringSubmatch(line); m != nil {
+			warnings = append(warnings, Warning{Tool: "nextpnr", Severity: "warning", Message: m[1]})
+		}
+	}
+
+	return BuildReport{
+		Utilization: utilization,
+		Timing:      timing,
+		Warnings:    warnings,
+		Artifacts: Artifacts{
+			BitstreamBytes: bitstreamBytes,
+			AscBytes:       ascBytes,
+			JSONBytes:      jsonBytes,
+		},
+	}
+}
+
+// applyNextpnrUtilization fills in the Available/Percent side of
+// whichever ResourceUsage a nextpnr device-name corresponds to; the
+// Used side was already populated from yosys's post-synthesis cell
+// counts, which is the number actually placed rather than nextpnr's
+// coarser per-device-cell-type bucket.
+func applyNextpnrUtilization(u *Utilization, device string, used, available int, percent float64) {
+	switch device {
+	case "ICESTORM_LC":
+		u.LUT4.Available = available
+		u.LUT4.Percent = percent
+		u.DFF.Available = available
+		// ICE40 DFFs share the same logic cell resource pool as LUTs, so
+		// nextpnr never reports a DFF-specific utilization line; reuse
+		// the LC percentage as an approximation rather than leaving it
+		// at zero, which would read as "0% used".
+		u.DFF.Percent = percent
+	case "ICESTORM_RAM":
+		u.BRAM.Available = available
+		u.BRAM.Percent = percent
+	case "SB_MAC16":
+		u.DSP.Available = available
+		u.DSP.Percent = percent
+	}
+}
+
+func sumCellCounts(counts map[string]int, prefix string) int {
+	total := 0
+	for cell, n := range counts {
+		if strings.HasPrefix(cell, prefix) {
+			total += n
+		}
+	}
+	return total
+}
+
+func atoiOr0(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
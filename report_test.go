@@ -0,0 +1,114 @@
+package main
+
+import "testing"
+
+func TestBuildReportParsesYosysCellCountsAndWarnings(t *testing.T) {
+	yosysLog := `
+     SB_LUT4                          600
+     SB_DFF                           120
+     SB_DFFE                           30
+     SB_RAM40_4K                        2
+Warning: wire foo has an unconnected port
+`
+	report := buildReport(yosysLog, "", "ice40up5k", 4096, 2048, 1024)
+
+	u := report.Utilization
+	if u.LUT4.Used != 600 {
+		t.Errorf("LUT4.Used = %d, want 600", u.LUT4.Used)
+	}
+	if u.DFF.Used != 150 {
+		t.Errorf("DFF.Used = %d, want 150 (SB_DFF + SB_DFFE)", u.DFF.Used)
+	}
+	if u.BRAM.Used != 2 {
+		t.Errorf("BRAM.Used = %d, want 2", u.BRAM.Used)
+	}
+
+	if len(report.Warnings) != 1 {
+		t.Fatalf("len(Warnings) = %d, want 1", len(report.Warnings))
+	}
+	if report.Warnings[0].Tool != "yosys" || report.Warnings[0].Message != "wire foo has an unconnected port" {
+		t.Errorf("Warnings[0] = %+v, unexpected", report.Warnings[0])
+	}
+
+	if report.Artifacts.BitstreamBytes != 4096 || report.Artifacts.AscBytes != 2048 || report.Artifacts.JSONBytes != 1024 {
+		t.Errorf("Artifacts = %+v, unexpected", report.Artifacts)
+	}
+}
+
+func TestBuildReportParsesNextpnrUtilizationFmaxAndWarnings(t *testing.T) {
+	nextpnrLog := `
+Info: 	         ICESTORM_LC:  1234/ 5280    23%
+Info: 	        ICESTORM_RAM:     2/   30     6%
+Info: 	            SB_MAC16:     1/    8    12%
+Info: Max frequency for clock 'clk': 55.28 MHz (PASS at 12.00 MHz)
+Warning: IO 'uio_in[3]' is unconstrained in PCF, ignoring
+`
+	report := buildReport("", nextpnrLog, "ice40up5k", 0, 0, 0)
+
+	u := report.Utilization
+	if u.LUT4.Available != 5280 || u.LUT4.Percent != 23 {
+		t.Errorf("LUT4 = %+v, want Available 5280 Percent 23", u.LUT4)
+	}
+	if u.DFF.Available != 5280 || u.DFF.Percent != 23 {
+		t.Errorf("DFF = %+v, want Available 5280 Percent 23 (shares the LC pool)", u.DFF)
+	}
+	if u.BRAM.Available != 30 || u.BRAM.Percent != 6 {
+		t.Errorf("BRAM = %+v, want Available 30 Percent 6", u.BRAM)
+	}
+	if u.DSP.Available != 8 || u.DSP.Percent != 12 {
+		t.Errorf("DSP = %+v, want Available 8 Percent 12", u.DSP)
+	}
+
+	if len(report.Timing) != 1 {
+		t.Fatalf("len(Timing) = %d, want 1", len(report.Timing))
+	}
+	timing := report.Timing[0]
+	if timing.ClockName != "clk" || timing.AchievedMHz != 55.28 || timing.RequestedMHz != 12 {
+		t.Errorf("Timing[0] = %+v, unexpected", timing)
+	}
+
+	if len(report.Warnings) != 1 || report.Warnings[0].Tool != "nextpnr" {
+		t.Fatalf("Warnings = %+v, want one nextpnr warning", report.Warnings)
+	}
+	if report.Warnings[0].Message != "IO 'uio_in[3]' is unconstrained in PCF, ignoring" {
+		t.Errorf("Warnings[0].Message = %q, unexpected", report.Warnings[0].Message)
+	}
+}
+
+func TestApplyNextpnrUtilizationDSP(t *testing.T) {
+	var u Utilization
+	applyNextpnrUtilization(&u, "SB_MAC16", 4, 8, 50)
+
+	if u.DSP.Available != 8 || u.DSP.Percent != 50 {
+		t.Errorf("DSP = %+v, want Available 8 Percent 50", u.DSP)
+	}
+}
+
+func TestBuildReportLeavesUtilizationZeroedForUnsupportedTarget(t *testing.T) {
+	yosysLog := `
+     TRELLIS_COMB                     600
+     TRELLIS_FF                       120
+`
+	nextpnrLog := `
+Info: 	         TRELLIS_COMB:  1234/ 5280    23%
+`
+	report := buildReport(yosysLog, nextpnrLog, "ecp5", 4096, 2048, 1024)
+
+	u := report.Utilization
+	if u != (Utilization{}) {
+		t.Errorf("Utilization = %+v, want zero value for a target with no known cell/device names", u)
+	}
+
+	if len(report.Warnings) != 1 || report.Warnings[0].Tool != "fpga-compiler" {
+		t.Fatalf("Warnings = %+v, want one fpga-compiler warning explaining the gap", report.Warnings)
+	}
+}
+
+func TestApplyNextpnrUtilizationUnknownDeviceIsIgnored(t *testing.T) {
+	u := Utilization{DSP: ResourceUsage{Used: 3}}
+	applyNextpnrUtilization(&u, "SOME_OTHER_DEVICE", 1, 2, 99)
+
+	if u.LUT4.Available != 0 || u.DFF.Available != 0 || u.BRAM.Available != 0 || u.DSP.Available != 0 {
+		t.Errorf("an unrecognized device name should not populate any resource: %+v", u)
+	}
+}
@@ -0,0 +1,142 @@
+// s3cache.go implements CompilationCache on an S3-compatible bucket, for
+// deployments that want the compilation cache shared across multiple
+// server instances rather than pinned to one node's filesystem.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Cache persists cache entries as objects in an S3-compatible bucket,
+// evicting the least-recently-modified ones once the bucket's total
+// object size exceeds maxBytes.
+type S3Cache struct {
+	client   *minio.Client
+	bucket   string
+	maxBytes int64
+}
+
+func newS3Cache() (*S3Cache, error) {
+	endpoint := os.Getenv("CACHE_MINIO_ENDPOINT")
+	if endpoint == "" {
+		return nil, fmt.Errorf("CACHE_MINIO_ENDPOINT is required when COMPILATION_CACHE=s3")
+	}
+	bucket := os.Getenv("CACHE_MINIO_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("CACHE_MINIO_BUCKET is required when COMPILATION_CACHE=s3")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("CACHE_MINIO_ACCESS_KEY"), os.Getenv("CACHE_MINIO_SECRET_KEY"), ""),
+		Secure: envOr("CACHE_MINIO_USE_SSL", "true") == "true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+	}
+
+	return &S3Cache{
+		client:   client,
+		bucket:   bucket,
+		maxBytes: envInt64("COMPILATION_CACHE_MAX_BYTES", 10<<30), // 10GiB
+	}, nil
+}
+
+func (c *S3Cache) key(key string) string { return key + ".json" }
+
+func (c *S3Cache) Get(key string) (*CacheEntry, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+
+	obj, err := c.client.GetObject(ctx, c.bucket, c.key(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, false
+	}
+	defer obj.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(obj); err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *S3Cache) Put(key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	if _, err := c.client.PutObject(ctx, c.bucket, c.key(key), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	}); err != nil {
+		return err
+	}
+
+	// Eviction doesn't need to hold up the response to the caller.
+	go c.evict()
+	return nil
+}
+
+// evict removes the least-recently-modified cache objects until the
+// bucket's total size is back under maxBytes.
+func (c *S3Cache) evict() {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+
+	type object struct {
+		key  string
+		size int64
+		unix int64
+	}
+	var objects []object
+	var total int64
+
+	for obj := range c.client.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{}) {
+		if obj.Err != nil {
+			return
+		}
+		objects = append(objects, object{key: obj.Key, size: obj.Size, unix: obj.LastModified.UnixNano()})
+		total += obj.Size
+	}
+	if total <= c.maxBytes {
+		return
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].unix < objects[j].unix })
+	for _, obj := range objects {
+		if total <= c.maxBytes {
+			return
+		}
+		if err := c.client.RemoveObject(ctx, c.bucket, obj.key, minio.RemoveObjectOptions{}); err != nil {
+			continue
+		}
+		total -= obj.size
+	}
+}
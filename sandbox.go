@@ -0,0 +1,233 @@
+// sandbox.go implements SandboxExecutor: it isolates each toolchain
+// command inside a bwrap or nsjail sandbox with a read-only rootfs, a
+// writable work directory, no network, CPU/memory/file-size rlimits
+// enforced on the child via reexec.go, and a dedicated cgroup-v2 slice
+// for memory/CPU accounting.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// SandboxExecutor runs commands under the configured backend (bwrap or
+// nsjail). CPU/memory/file-size rlimits are applied by re-executing this
+// same binary inside the sandbox with sandboxReexecFlag; that reexec
+// (see reexec.go) sets the rlimits on itself and then execve's the real
+// toolchain command, so the limits bind the process that actually runs.
+type SandboxExecutor struct {
+	backend     string
+	binary      string
+	cpuSeconds  int
+	memoryBytes int64
+	fsizeBytes  int64
+	cgroupRoot  string
+}
+
+var cgroupSeq int64
+
+// newSandboxExecutor validates that the configured sandbox backend binary
+// and cgroup-v2 delegation are available, refusing to start otherwise —
+// a misconfigured sandbox must not silently fall back to running
+// untrusted Verilog unsandboxed.
+func newSandboxExecutor() (*SandboxExecutor, error) {
+	backend := envOr("SANDBOX_BACKEND", "bwrap")
+	if backend != "bwrap" && backend != "nsjail" {
+		return nil, fmt.Errorf("unknown SANDBOX_BACKEND %q (want bwrap or nsjail)", backend)
+	}
+
+	binary, err := exec.LookPath(backend)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox backend %q not found in PATH: %w", backend, err)
+	}
+
+	cgroupRoot := envOr("SANDBOX_CGROUP_ROOT", "/sys/fs/cgroup/fpga-compiler")
+	if err := os.MkdirAll(cgroupRoot, 0755); err != nil {
+		return nil, fmt.Errorf("cgroup-v2 delegation not available at %s: %w", cgroupRoot, err)
+	}
+
+	return &SandboxExecutor{
+		backend:     backend,
+		binary:      binary,
+		cpuSeconds:  envInt("SANDBOX_RLIMIT_CPU_SECONDS", 60),
+		memoryBytes: envInt64("SANDBOX_RLIMIT_MEMORY_BYTES", 1<<30),  // 1GiB
+		fsizeBytes:  envInt64("SANDBOX_RLIMIT_FSIZE_BYTES", 256<<20), // 256MiB
+		cgroupRoot:  cgroupRoot,
+	}, nil
+}
+
+func (s *SandboxExecutor) Run(ctx context.Context, sink messageSink, workDir, command string, args []string) ExecResult {
+	cgroupPath, release, err := s.newJobCgroup()
+	if err != nil {
+		return ExecResult{Err: fmt.Errorf("failed to set up cgroup: %w", err)}
+	}
+	defer release()
+
+	self, err := os.Executable()
+	if err != nil {
+		return ExecResult{Err: fmt.Errorf("failed to resolve own executable: %w", err)}
+	}
+	selfDir := filepath.Dir(self)
+	innerArgv := append([]string{self, sandboxReexecFlag, command}, args...)
+
+	var sandboxArgs []string
+	switch s.backend {
+	case "bwrap":
+		sandboxArgs = s.bwrapArgs(workDir, selfDir)
+	case "nsjail":
+		chrootDir, releaseChroot, err := s.newNsjailChroot()
+		if err != nil {
+			return ExecResult{Err: fmt.Errorf("failed to set up nsjail chroot: %w", err)}
+		}
+		defer releaseChroot()
+		sandboxArgs = s.nsjailArgs(workDir, cgroupPath, chrootDir, selfDir)
+	}
+
+	cmd := exec.CommandContext(ctx, s.binary, append(sandboxArgs, innerArgv...)...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envRlimitCPU, s.cpuSeconds),
+		fmt.Sprintf("%s=%d", envRlimitAS, s.memoryBytes),
+		fmt.Sprintf("%s=%d", envRlimitFSize, s.fsizeBytes),
+	)
+
+	result := runCmd(sink, cmd, func(pid int) { s.joinJobCgroup(cgroupPath, pid) })
+	if !result.Ok() {
+		s.classifyFailure(&result, cgroupPath)
+	}
+	return result
+}
+
+// joinJobCgroup adds pid to cgroupPath so memory.max is actually
+// enforced against it. A cgroup-v2 leaf with nothing in cgroup.procs
+// accounts and enforces nothing, so this must run for both backends:
+// nsjail also takes --cgroupv2_mount, but doesn't write cgroup.procs
+// for us, and bwrap's sandboxed process inherits its cgroup from pid
+// alone once it joins. Children the sandboxed process forks inherit
+// its cgroup automatically, so joining the top-level sandbox PID is
+// enough. Best-effort: a failure here is logged, not fatal, since the
+// rlimits applied via reexec.go still bound the process independently.
+func (s *SandboxExecutor) joinJobCgroup(cgroupPath string, pid int) {
+	procsPath := filepath.Join(cgroupPath, "cgroup.procs")
+	if err := os.WriteFile(procsPath, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		log.Printf("failed to add pid %d to job cgroup %s: %v", pid, cgroupPath, err)
+	}
+}
+
+// bwrapArgs confines the child to a read-only view of the host rootfs
+// plus the directory the server binary itself lives in (so the reexec
+// below can find it when it isn't installed under /usr, /bin or /lib),
+// a tmpfs /tmp, a writable bind of workDir, and no network or PID
+// namespace sharing.
+func (s *SandboxExecutor) bwrapArgs(workDir, selfDir string) []string {
+	return []string{
+		"--ro-bind", "/usr", "/usr",
+		"--ro-bind", "/bin", "/bin",
+		"--ro-bind", "/lib", "/lib",
+		"--ro-bind-try", "/lib64", "/lib64",
+		"--ro-bind", selfDir, selfDir,
+		"--tmpfs", "/tmp",
+		"--bind", workDir, workDir,
+		"--chdir", workDir,
+		"--unshare-net",
+		"--unshare-pid",
+		"--die-with-parent",
+		"--",
+	}
+}
+
+// newNsjailChroot creates an empty directory to use as the nsjail
+// --chroot target. nsjail bind-mounts sources into this tree before
+// pivoting into it, so (unlike chrooting straight to "/") the sandboxed
+// process only ever sees the paths we explicitly bind, mirroring
+// bwrapArgs's read-only rootfs view. The returned release func removes
+// it once the job has finished.
+func (s *SandboxExecutor) newNsjailChroot() (path string, release func(), err error) {
+	dir, err := os.MkdirTemp("", "fpga-compiler-nsjail-root-")
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// nsjailArgs chroots into an empty directory and bind-mounts the same
+// minimal read-only rootfs as bwrapArgs into it, plus the directory the
+// server binary lives in, rather than chrooting to the real host root
+// (which would give the sandboxed process full filesystem visibility).
+// It additionally passes native rlimit and cgroup-v2 flags: nsjail
+// enforces these itself, on top of the reexec helper applying them
+// again inside the sandboxed process.
+func (s *SandboxExecutor) nsjailArgs(workDir, cgroupPath, chrootDir, selfDir string) []string {
+	return []string{
+		"--mode", "o",
+		"--chroot", chrootDir,
+		"--bindmount_ro", "/usr:/usr",
+		"--bindmount_ro", "/bin:/bin",
+		"--bindmount_ro", "/lib:/lib",
+		"--bindmount_ro", "/lib64:/lib64",
+		"--bindmount_ro", selfDir + ":" + selfDir,
+		"--cwd", workDir,
+		"--bindmount", workDir + ":" + workDir,
+		"--rlimit_cpu", strconv.Itoa(s.cpuSeconds),
+		"--rlimit_as", strconv.FormatInt(s.memoryBytes/(1<<20), 10),
+		"--rlimit_fsize", strconv.FormatInt(s.fsizeBytes/(1<<20), 10),
+		"--cgroup_mem_max", strconv.FormatInt(s.memoryBytes, 10),
+		"--cgroupv2_mount", s.cgroupRoot,
+		"--",
+	}
+}
+
+// newJobCgroup creates a per-job cgroup-v2 leaf under cgroupRoot so
+// memory usage can be accounted, and enforced via memory.max,
+// independent of the rlimits applied to the sandboxed process itself.
+// The returned release func removes it once the job has finished.
+func (s *SandboxExecutor) newJobCgroup() (path string, release func(), err error) {
+	id := atomic.AddInt64(&cgroupSeq, 1)
+	path = filepath.Join(s.cgroupRoot, fmt.Sprintf("job-%d-%d", os.Getpid(), id))
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", nil, err
+	}
+	if err := os.WriteFile(filepath.Join(path, "memory.max"), []byte(strconv.FormatInt(s.memoryBytes, 10)), 0644); err != nil {
+		os.Remove(path)
+		return "", nil, fmt.Errorf("failed to set memory.max: %w", err)
+	}
+	return path, func() { os.Remove(path) }, nil
+}
+
+// classifyFailure marks result as an OOM kill when the job's cgroup
+// memory.events records one and the exit status hasn't already
+// classified the failure as an OOM kill or rlimit violation.
+func (s *SandboxExecutor) classifyFailure(result *ExecResult, cgroupPath string) {
+	if result.RLimitViolation != "" || result.OOMKilled {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(cgroupPath, "memory.events"))
+	if err != nil {
+		return
+	}
+	if cgroupOOMKillCount(data) > 0 {
+		result.OOMKilled = true
+	}
+}
+
+// cgroupOOMKillCount parses the "oom_kill <n>" line out of a cgroup-v2
+// memory.events file.
+func cgroupOOMKillCount(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			n, err := strconv.Atoi(fields[1])
+			if err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSandboxExecutorRejectsUnknownBackend(t *testing.T) {
+	t.Setenv("SANDBOX_BACKEND", "qemu")
+
+	_, err := newSandboxExecutor()
+	if err == nil {
+		t.Fatal("newSandboxExecutor with an unknown backend should fail, got nil error")
+	}
+}
+
+func TestNewSandboxExecutorRejectsMissingBinary(t *testing.T) {
+	t.Setenv("SANDBOX_BACKEND", "bwrap")
+	t.Setenv("PATH", t.TempDir()) // scoped PATH with no bwrap on it
+
+	_, err := newSandboxExecutor()
+	if err == nil {
+		t.Fatal("newSandboxExecutor should fail when the backend binary isn't in PATH, got nil error")
+	}
+}
+
+func TestNewSandboxExecutorRejectsUnwritableCgroupRoot(t *testing.T) {
+	t.Setenv("SANDBOX_BACKEND", "bwrap")
+
+	// A file where a path component must be a directory makes MkdirAll
+	// fail exactly like an undelegated cgroup-v2 mount would.
+	blocker := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(blocker, []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("SANDBOX_CGROUP_ROOT", filepath.Join(blocker, "fpga-compiler"))
+
+	_, err := newSandboxExecutor()
+	if err == nil {
+		t.Fatal("newSandboxExecutor should fail when SANDBOX_CGROUP_ROOT can't be created, got nil error")
+	}
+}
+
+func TestNewSandboxExecutorSucceedsWithValidConfig(t *testing.T) {
+	t.Setenv("SANDBOX_BACKEND", "bwrap")
+	t.Setenv("SANDBOX_CGROUP_ROOT", filepath.Join(t.TempDir(), "fpga-compiler"))
+	t.Setenv("SANDBOX_RLIMIT_CPU_SECONDS", "30")
+
+	// bwrap must actually be resolvable on the test host's PATH for this
+	// case to exercise the success path instead of the LookPath failure.
+	if _, err := exec.LookPath("bwrap"); err != nil {
+		t.Skip("bwrap not installed on test host")
+	}
+
+	s, err := newSandboxExecutor()
+	if err != nil {
+		t.Fatalf("newSandboxExecutor: %v", err)
+	}
+	if s.backend != "bwrap" {
+		t.Errorf("backend = %q, want bwrap", s.backend)
+	}
+	if s.cpuSeconds != 30 {
+		t.Errorf("cpuSeconds = %d, want 30 (from SANDBOX_RLIMIT_CPU_SECONDS)", s.cpuSeconds)
+	}
+}
@@ -0,0 +1,76 @@
+// sink.go implements the messageSink used while a compilation cache
+// miss runs: it tees each StreamMessage to the live job (for its SSE
+// subscribers) and to a transcriptRecorder, so an identical future
+// request can replay this run from cache.
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// teeSink publishes every message to both a live Job and a
+// transcriptRecorder building up the entry that will be cached once the
+// run succeeds.
+type teeSink struct {
+	job      *Job
+	recorder *transcriptRecorder
+}
+
+func (s teeSink) publish(msg StreamMessage) {
+	s.job.publish(msg)
+	s.recorder.publish(msg)
+}
+
+// transcriptRecorder buffers every message published during a live
+// compilation along with its elapsed time since the run started. publish
+// is called concurrently from the stdout and stderr streamOutput
+// goroutines of every command that runs during the compilation (see
+// runCmd in executor.go), so messages and its mutex guard access to it.
+type transcriptRecorder struct {
+	start time.Time
+
+	mu       sync.Mutex
+	messages []cachedMessage
+}
+
+func newTranscriptRecorder(start time.Time) *transcriptRecorder {
+	return &transcriptRecorder{start: start}
+}
+
+func (r *transcriptRecorder) publish(msg StreamMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, cachedMessage{Msg: msg, Elapsed: time.Since(r.start)})
+}
+
+// captureSink tees stdout/stderr StreamMessages into buf, in addition to
+// forwarding every message to the wrapped sink, so a command's combined
+// output can be parsed after the fact (see report.go) without changing
+// how it's streamed live. Like transcriptRecorder, publish is called
+// concurrently from a command's stdout and stderr streamOutput
+// goroutines, so buf is guarded by mu; strings.Builder itself is not
+// safe for concurrent use.
+type captureSink struct {
+	messageSink
+	mu  sync.Mutex
+	buf *strings.Builder
+}
+
+func (s *captureSink) publish(msg StreamMessage) {
+	if msg.Type == "stdout" || msg.Type == "stderr" {
+		s.mu.Lock()
+		s.buf.WriteString(msg.Data)
+		s.mu.Unlock()
+	}
+	s.messageSink.publish(msg)
+}
+
+// String returns the accumulated captured output. Safe to call once the
+// command that was writing to s has finished.
+func (s *captureSink) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
@@ -0,0 +1,143 @@
+// store.go defines the pluggable artifact storage backends used to persist
+// compiled bitstreams beyond the lifetime of the request that produced
+// them: a local filesystem store for single-node deployments, and an
+// S3-compatible store (via minio-go) for horizontally scaled ones.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ArtifactStore persists and retrieves the bitstream produced by a
+// compilation job, keyed by job ID.
+type ArtifactStore interface {
+	Put(jobID string, data []byte) error
+	Get(jobID string) ([]byte, error)
+	Delete(jobID string) error
+}
+
+// newArtifactStore selects an ArtifactStore implementation based on the
+// ARTIFACT_STORE environment variable ("fs", the default, or "s3").
+func newArtifactStore() (ArtifactStore, error) {
+	switch os.Getenv("ARTIFACT_STORE") {
+	case "s3":
+		return newS3Store()
+	case "", "fs":
+		return newFSStore(envOr("ARTIFACT_STORE_DIR", "/var/lib/fpga-compiler/artifacts"))
+	default:
+		return nil, fmt.Errorf("unknown ARTIFACT_STORE %q", os.Getenv("ARTIFACT_STORE"))
+	}
+}
+
+// FSStore persists artifacts as plain files under a root directory.
+type FSStore struct {
+	root string
+}
+
+func newFSStore(root string) (*FSStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact store root %s: %w", root, err)
+	}
+	return &FSStore{root: root}, nil
+}
+
+func (s *FSStore) path(jobID string) string {
+	return filepath.Join(s.root, jobID+".bin")
+}
+
+func (s *FSStore) Put(jobID string, data []byte) error {
+	return os.WriteFile(s.path(jobID), data, 0644)
+}
+
+func (s *FSStore) Get(jobID string) ([]byte, error) {
+	return os.ReadFile(s.path(jobID))
+}
+
+func (s *FSStore) Delete(jobID string) error {
+	err := os.Remove(s.path(jobID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// S3Store persists artifacts as objects in an S3-compatible bucket.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+func newS3Store() (*S3Store, error) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		return nil, fmt.Errorf("MINIO_ENDPOINT is required when ARTIFACT_STORE=s3")
+	}
+	bucket := os.Getenv("MINIO_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("MINIO_BUCKET is required when ARTIFACT_STORE=s3")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("MINIO_ACCESS_KEY"), os.Getenv("MINIO_SECRET_KEY"), ""),
+		Secure: envOr("MINIO_USE_SSL", "true") == "true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+	}
+
+	return &S3Store{client: client, bucket: bucket}, nil
+}
+
+func (s *S3Store) key(jobID string) string {
+	return jobID + ".bin"
+}
+
+func (s *S3Store) Put(jobID string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(jobID), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+func (s *S3Store) Get(jobID string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	obj, err := s.client.GetObject(ctx, s.bucket, s.key(jobID), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *S3Store) Delete(jobID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), artifactOpTimeout)
+	defer cancel()
+	return s.client.RemoveObject(ctx, s.bucket, s.key(jobID), minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/TinyTapeout/tt-fpga-compiler/internal/toolchain"
+)
+
+func TestValidateTargetOptionsRejectsMissingRequiredKey(t *testing.T) {
+	err := validateTargetOptions(toolchain.ECP5{}, map[string]string{"device": "LFE5U-25F"})
+	if err == nil {
+		t.Fatal("validateTargetOptions should reject a missing required key (package), got nil error")
+	}
+}
+
+func TestValidateTargetOptionsAcceptsAllRequiredKeysPresent(t *testing.T) {
+	err := validateTargetOptions(toolchain.ECP5{}, map[string]string{"device": "LFE5U-25F", "package": "CABGA381"})
+	if err != nil {
+		t.Errorf("validateTargetOptions with all required keys present = %v, want nil", err)
+	}
+}
+
+func TestValidateTargetOptionsAcceptsNoRequiredOptions(t *testing.T) {
+	err := validateTargetOptions(toolchain.ICE40UP5K{}, map[string]string{})
+	if err != nil {
+		t.Errorf("validateTargetOptions for a target with no RequiredOptions = %v, want nil", err)
+	}
+}
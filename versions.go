@@ -0,0 +1,51 @@
+// versions.go probes the installed toolchain binaries' version strings
+// once at startup so they can be folded into the compilation cache key:
+// upgrading a toolchain then invalidates existing cache entries
+// automatically instead of silently serving stale bitstreams.
+package main
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+)
+
+// versionProbes lists, for each toolchain binary the server might
+// invoke, the flag that prints its version.
+var versionProbes = map[string][]string{
+	"yosys":              {"-V"},
+	"nextpnr-ice40":      {"--version"},
+	"nextpnr-ecp5":       {"--version"},
+	"nextpnr-himbaechel": {"--version"},
+	"icepack":            {"-V"},
+	"ecppack":            {"--version"},
+	"gowin_pack":         {"--version"},
+}
+
+// probeToolchainVersions runs each configured probe and returns the
+// first line of its output, keyed by binary name. A binary that isn't
+// installed, or that exits with an error without printing anything, is
+// recorded as "unknown" rather than failing startup, since not every
+// deployment needs every target's toolchain installed. A binary that
+// exits with an error but prints output is recorded by that output's
+// first line.
+func probeToolchainVersions() map[string]string {
+	versions := make(map[string]string, len(versionProbes))
+	for bin, args := range versionProbes {
+		versions[bin] = probeVersion(bin, args)
+	}
+	return versions
+}
+
+func probeVersion(bin string, args []string) string {
+	out, err := exec.Command(bin, args...).CombinedOutput()
+	if err != nil && len(out) == 0 {
+		return "unknown"
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return "unknown"
+}